@@ -0,0 +1,323 @@
+package slogdriver
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Format is the output format of the handler created by NewHandler.
+type Format int
+
+const (
+	// FormatJSON writes the structured log of Cloud Logging. It is the default.
+	FormatJSON Format = iota
+	// FormatConsole writes a human-readable line per record for local development.
+	// It has the timestamp, the colored severity, the message, the short source location,
+	// the trace ID and the labels, followed by the attributes.
+	FormatConsole
+	// FormatAuto selects FormatConsole when the writer is a terminal and the process isn't running on Google Cloud,
+	// otherwise FormatJSON.
+	FormatAuto
+)
+
+// resolve returns FormatJSON or FormatConsole for w.
+func (f Format) resolve(w io.Writer) Format {
+	if f != FormatAuto {
+		return f
+	}
+	if isTerminal(w) && !onGoogleCloud() {
+		return FormatConsole
+	}
+	return FormatJSON
+}
+
+// onGoogleCloud reports whether the process is running on Cloud Run, App Engine, Cloud Functions or GKE.
+func onGoogleCloud() bool {
+	for _, key := range []string{"K_SERVICE", "CLOUD_RUN_JOB", "GAE_SERVICE", "FUNCTION_TARGET", "KUBERNETES_SERVICE_HOST"} {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+const consoleTimeFormat = "2006-01-02 15:04:05.000"
+
+// consoleHandler writes the records shaped by cloudLoggingHandler in a human-readable format.
+// It is always wrapped by cloudLoggingHandler, so the special fields of Cloud Logging are the top-level attributes.
+type consoleHandler struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	opts   HandlerOptions
+	color  bool
+	attrs  []slog.Attr
+	groups []string
+}
+
+func newConsoleHandler(w io.Writer, opts HandlerOptions) *consoleHandler {
+	return &consoleHandler{
+		w:     w,
+		mu:    &sync.Mutex{},
+		opts:  opts,
+		color: isTerminal(w),
+	}
+}
+
+var _ slog.Handler = (*consoleHandler)(nil)
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var (
+		severity string
+		source   string
+		trace    string
+		labels   []slog.Attr
+		attrs    bytes.Buffer
+	)
+
+	handleAttr := func(a slog.Attr) {
+		a.Value = a.Value.Resolve()
+		switch a.Key {
+		case SeverityKey:
+			severity = a.Value.String()
+			return
+		case SourceLocationKey:
+			if loc, ok := a.Value.Any().(LogEntrySourceLocation); ok {
+				source = filepath.Base(loc.File) + ":" + loc.Line
+			}
+			return
+		case TraceKey:
+			s := a.Value.String()
+			trace = s[strings.LastIndex(s, "/")+1:]
+			return
+		case SpanIDKey, TraceSampledKey:
+			return
+		case LabelKey:
+			if a.Value.Kind() == slog.KindGroup {
+				labels = append(labels, a.Value.Group()...)
+				return
+			}
+		}
+		h.appendAttr(&attrs, nil, a)
+	}
+	for _, a := range h.attrs {
+		handleAttr(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		if len(h.groups) > 0 {
+			h.appendAttr(&attrs, h.groups, a)
+			return true
+		}
+		handleAttr(a)
+		return true
+	})
+
+	if severity == "" {
+		severity = h.opts.SeverityMapper(r.Level)
+	}
+
+	var buf bytes.Buffer
+	if !r.Time.IsZero() {
+		buf.WriteString(r.Time.Format(consoleTimeFormat))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(h.colorize(severityColor(severity), fmt.Sprintf("%-9s", severity)))
+	buf.WriteByte(' ')
+	buf.WriteString(r.Message)
+	if source != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(h.colorize(colorGray, source))
+	}
+	if trace != "" {
+		buf.WriteString(" trace=")
+		buf.WriteString(trace)
+	}
+	if len(labels) > 0 {
+		buf.WriteString(" [")
+		for i, l := range labels {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(l.Key + "=" + quoteIfNeeded(consoleValue(l.Value.Resolve())))
+		}
+		buf.WriteByte(']')
+	}
+	buf.Write(attrs.Bytes())
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+// appendAttr appends " key=value" to buf. The groups are flattened into dotted keys, and the empty groups are elided.
+func (h *consoleHandler) appendAttr(buf *bytes.Buffer, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(buf, groups, ga)
+		}
+		return
+	}
+
+	if a.Key == "" {
+		return
+	}
+
+	buf.WriteByte(' ')
+	key := strings.Join(append(groups[:len(groups):len(groups)], a.Key), ".")
+	buf.WriteString(h.colorize(colorGray, key+"="))
+	buf.WriteString(quoteIfNeeded(consoleValue(a.Value)))
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	if len(h.groups) > 0 {
+		attrs = []slog.Attr{nestGroups(h.groups, attrs)}
+	}
+	h2.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)
+	return &h2
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &h2
+}
+
+// Sync flushes the writer if it has Sync or Flush method.
+func (h *consoleHandler) Sync() error {
+	switch w := h.w.(type) {
+	case interface{ Sync() error }:
+		return w.Sync()
+	case interface{ Flush() error }:
+		return w.Flush()
+	}
+	return nil
+}
+
+func nestGroups(groups []string, attrs []slog.Attr) slog.Attr {
+	a := slog.Attr{Key: groups[len(groups)-1], Value: slog.GroupValue(attrs...)}
+	for i := len(groups) - 2; i >= 0; i-- {
+		a = slog.Attr{Key: groups[i], Value: slog.GroupValue(a)}
+	}
+	return a
+}
+
+func consoleValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		switch a := v.Any().(type) {
+		case error:
+			return a.Error()
+		case encoding.TextMarshaler:
+			if b, err := a.MarshalText(); err == nil {
+				return string(b)
+			}
+		case []byte:
+			return string(a)
+		}
+		if b, err := json.Marshal(v.Any()); err == nil {
+			return string(b)
+		}
+	}
+	return v.String()
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+const (
+	colorGray      = "90"
+	colorRed       = "31"
+	colorGreen     = "32"
+	colorYellow    = "33"
+	colorBlue      = "34"
+	colorCyan      = "36"
+	colorBoldRed   = "1;31"
+	colorMagenta   = "35"
+	colorBoldWhite = "1;37"
+)
+
+func severityColor(severity string) string {
+	switch severity {
+	case "DEFAULT", "DEBUG":
+		return colorGray
+	case "INFO":
+		return colorGreen
+	case "NOTICE":
+		return colorCyan
+	case "WARNING":
+		return colorYellow
+	case "ERROR":
+		return colorRed
+	case "CRITICAL":
+		return colorBoldRed
+	case "ALERT":
+		return colorMagenta
+	case "EMERGENCY":
+		return colorBoldWhite
+	default:
+		return colorBlue
+	}
+}
+
+func (h *consoleHandler) colorize(color, s string) string {
+	if !h.color {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
@@ -0,0 +1,79 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestFormatConsole(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		Format:        slogdriver.FormatConsole,
+		AddSource:     true,
+		ProjectID:     "test-project",
+		DefaultLabels: []slog.Attr{slog.String("env", "dev")},
+	})
+	ctx := slogdriver.ContextWithTrace(context.Background(), slogdriver.TraceContext{TraceID: "105445aa7843bc8bf206b12000100000"})
+
+	logger.With("common", 1).WithGroup("g").ErrorContext(ctx, "Hello World",
+		slog.Any("error", errors.New("something wrong")),
+		slog.Group(slogdriver.LabelKey, slog.String("key", "value")),
+	)
+
+	got := buf.String()
+	expects := []string{
+		"ERROR     Hello World ",
+		"console_test.go:",
+		" trace=105445aa7843bc8bf206b12000100000",
+		" [env=dev key=value]",
+		" common=1",
+		` g.error="something wrong"`,
+	}
+	for _, expect := range expects {
+		if !strings.Contains(got, expect) {
+			t.Errorf("log should contain %q, got %q", expect, got)
+		}
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("log should not be colored when the writer isn't a terminal, got %q", got)
+	}
+	if strings.Count(got, "\n") != 1 {
+		t.Errorf("log should be one line, got %q", got)
+	}
+}
+
+func TestFormatConsole_ReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		Format: slogdriver.FormatConsole,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "password" {
+				return slog.String(a.Key, "REDACTED")
+			}
+			return a
+		},
+	})
+	logger.Info("Hello World", slog.String("password", "secret"))
+
+	if got := buf.String(); !strings.Contains(got, " password=REDACTED") {
+		t.Errorf("password should be redacted, got %q", got)
+	}
+}
+
+func TestFormatAuto(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{Format: slogdriver.FormatAuto})
+	logger.Info("Hello World")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("log should be json when the writer isn't a terminal: %+v", err)
+	}
+}
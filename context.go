@@ -0,0 +1,110 @@
+package slogdriver
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+type (
+	contextAttrsKey  struct{}
+	contextLabelsKey struct{}
+)
+
+// ContextWithAttrs returns a copy of ctx which carries attrs in addition to the attributes already in ctx.
+// The handler adds them to each record logged with the context, as if they were passed to the log call.
+// So they are nested in the current groups, except for the special fields of Cloud Logging.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, contextAttrsKey{}, slices.Concat(AttrsFromContext(ctx), attrs))
+}
+
+// AttrsFromContext returns the attributes stored by ContextWithAttrs.
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(contextAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// ContextWithLabels returns a copy of ctx which carries labels in addition to the labels already in ctx.
+// The handler adds them to "logging.googleapis.com/labels" of each record logged with the context.
+func ContextWithLabels(ctx context.Context, labels ...slog.Attr) context.Context {
+	if len(labels) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, contextLabelsKey{}, slices.Concat(LabelsFromContext(ctx), labels))
+}
+
+// LabelsFromContext returns the labels stored by ContextWithLabels.
+func LabelsFromContext(ctx context.Context) []slog.Attr {
+	labels, _ := ctx.Value(contextLabelsKey{}).([]slog.Attr)
+	return labels
+}
+
+type loggerKey struct{}
+
+// IntoContext returns a copy of ctx which carries logger.
+func IntoContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored by IntoContext.
+// If ctx doesn't have a logger, it returns slog.Default().
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// BindContext returns a logger which uses the values of ctx, such as the trace, the attributes and the labels,
+// when the context of each log call doesn't have them.
+// So the records logged by the methods without context, like Info, have them too.
+func BindContext(logger *slog.Logger, ctx context.Context) *slog.Logger {
+	return slog.New(&boundHandler{Handler: logger.Handler(), ctx: ctx})
+}
+
+// boundHandler passes the context of each log call merged with ctx to Handler.
+type boundHandler struct {
+	slog.Handler
+	ctx context.Context
+}
+
+func (h *boundHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.Handler.Enabled(mergedContext{Context: ctx, fallback: h.ctx}, level)
+}
+
+func (h *boundHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.Handler.Handle(mergedContext{Context: ctx, fallback: h.ctx}, r)
+}
+
+func (h *boundHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &boundHandler{Handler: h.Handler.WithAttrs(attrs), ctx: h.ctx}
+}
+
+func (h *boundHandler) WithGroup(name string) slog.Handler {
+	return &boundHandler{Handler: h.Handler.WithGroup(name), ctx: h.ctx}
+}
+
+// Sync calls Sync of Handler if exists.
+func (h *boundHandler) Sync() error {
+	if s, ok := h.Handler.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// mergedContext is Context whose values fall back to the values of fallback.
+// The deadline and the cancellation are of Context.
+type mergedContext struct {
+	context.Context
+	fallback context.Context
+}
+
+func (c mergedContext) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.fallback.Value(key)
+}
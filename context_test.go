@@ -0,0 +1,114 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestContextWithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+
+	ctx := slogdriver.ContextWithAttrs(context.Background(), slog.String("tenant", "tenant-1"))
+	ctx = slogdriver.ContextWithAttrs(ctx, slog.String("user", "user-1"), slogdriver.MakeOperationAttr(slogdriver.Operation{ID: "op"}))
+	logger.With("common", "value").WithGroup("g").InfoContext(ctx, "Hello World", slog.String("key", "value"))
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	expectedGroup := map[string]any{"tenant": "tenant-1", "user": "user-1", "key": "value"}
+	if group, _ := got["g"].(map[string]any); !maps.Equal(group, expectedGroup) {
+		t.Errorf("attrs in the context should be nested in the group %v, got %v", expectedGroup, got["g"])
+	}
+	if got["common"] != "value" {
+		t.Errorf("unexpected common: %v", got["common"])
+	}
+	if _, ok := got[slogdriver.OperationKey]; !ok {
+		t.Errorf("operation in the context should be at the top level, got %v", got)
+	}
+}
+
+func TestContextWithLabels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		DefaultLabels: []slog.Attr{slog.String("defaultLabel", "hoge")},
+	})
+	logger = logger.With(slog.Group(slogdriver.LabelKey, slog.String("commonLabel", "fuga")))
+
+	ctx := slogdriver.ContextWithLabels(context.Background(), slog.String("contextLabel", "piyo"))
+	logger.WithGroup("g").InfoContext(ctx, "Hello World", slog.Group(slogdriver.LabelKey, slog.String("specifiedLabel", "hogera")))
+
+	var got struct {
+		Labels map[string]string `json:"logging.googleapis.com/labels"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	expected := map[string]string{
+		"defaultLabel":   "hoge",
+		"commonLabel":    "fuga",
+		"contextLabel":   "piyo",
+		"specifiedLabel": "hogera",
+	}
+	if !maps.Equal(got.Labels, expected) {
+		t.Errorf("labels should be %v, got %v", expected, got.Labels)
+	}
+}
+
+func TestContextWithAttrs_ShouldNotModifyParent(t *testing.T) {
+	parent := slogdriver.ContextWithAttrs(context.Background(), slog.String("a", "1"))
+	_ = slogdriver.ContextWithAttrs(parent, slog.String("b", "2"))
+
+	if got := slogdriver.AttrsFromContext(parent); len(got) != 1 {
+		t.Errorf("parent context should have 1 attr, got %v", got)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if got := slogdriver.FromContext(context.Background()); got != slog.Default() {
+		t.Errorf("FromContext should return slog.Default() when the context doesn't have a logger, got %v", got)
+	}
+
+	logger := slogdriver.New(&bytes.Buffer{}, slogdriver.HandlerOptions{})
+	ctx := slogdriver.IntoContext(context.Background(), logger)
+	if got := slogdriver.FromContext(ctx); got != logger {
+		t.Errorf("FromContext should return the logger in the context, got %v", got)
+	}
+}
+
+func TestBindContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{ProjectID: "test-project"})
+
+	ctx := slogdriver.ContextWithTrace(context.Background(), slogdriver.TraceContext{TraceID: "105445aa7843bc8bf206b12000100000"})
+	ctx = slogdriver.ContextWithLabels(ctx, slog.String("contextLabel", "piyo"))
+	slogdriver.BindContext(logger, ctx).With("key", "value").Info("Hello World")
+
+	var got struct {
+		Key    string            `json:"key"`
+		Trace  string            `json:"logging.googleapis.com/trace"`
+		Labels map[string]string `json:"logging.googleapis.com/labels"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got.Key != "value" {
+		t.Errorf("unexpected key: %s", got.Key)
+	}
+	if got.Trace != "projects/test-project/traces/105445aa7843bc8bf206b12000100000" {
+		t.Errorf("unexpected trace: %s", got.Trace)
+	}
+	if got.Labels["contextLabel"] != "piyo" {
+		t.Errorf("unexpected labels: %v", got.Labels)
+	}
+}
@@ -0,0 +1,382 @@
+package slogdriver
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxEntrySize is the default of HandlerOptions.MaxEntrySize.
+// It is a little smaller than the limit of 256 KB, to leave room for the metadata added by the logging agent.
+// https://cloud.google.com/logging/quotas#log-limits
+const DefaultMaxEntrySize = 250 * 1024
+
+// OversizePolicy decides how the handler writes the entries larger than HandlerOptions.MaxEntrySize.
+type OversizePolicy int
+
+const (
+	// OversizeNone writes the oversized entries as they are. It is the default.
+	OversizeNone OversizePolicy = iota
+	// OversizeTruncate truncates the longest strings of the entry, such as the message and the attributes,
+	// with a marker until the entry fits. The stack trace loses the message at its head before the frames.
+	OversizeTruncate
+	// OversizeSplit splits the message of the entry into several entries which have the same attributes
+	// and "logging.googleapis.com/split", so that Cloud Logging can reassemble them.
+	// The other strings are truncated if the entry doesn't fit without the message.
+	// Only the first entry has the fields of Error Reporting, so that the error is reported once.
+	OversizeSplit
+)
+
+// LogSplit is the information about the split entries.
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogSplit
+type LogSplit struct {
+	UID         string `json:"uid"`
+	Index       int    `json:"index"`
+	TotalSplits int    `json:"totalSplits"`
+}
+
+// truncatedMarker is appended to the truncated strings.
+const truncatedMarker = "...(truncated)"
+
+// entryWriter applies the OversizePolicy to each entry written by slog.JSONHandler.
+// slog.JSONHandler writes one entry by one Write call under its lock, so the split entries are written together.
+type entryWriter struct {
+	w       io.Writer
+	maxSize int
+	policy  OversizePolicy
+}
+
+func (w *entryWriter) Write(p []byte) (int, error) {
+	if w.policy == OversizeNone || len(p) <= w.maxSize {
+		return w.w.Write(p)
+	}
+
+	entry, err := decodeEntry(p)
+	if err != nil {
+		return w.w.Write(p)
+	}
+
+	var entries [][]byte
+	switch w.policy {
+	case OversizeTruncate:
+		entries = [][]byte{truncateEntry(entry, w.maxSize)}
+	case OversizeSplit:
+		entries = splitEntry(entry, w.maxSize)
+	}
+
+	for _, e := range entries {
+		if _, err := w.w.Write(e); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// jsonObject is a JSON object which keeps the order of the fields.
+type jsonObject []jsonField
+
+type jsonField struct {
+	Key   string
+	Value any
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encodeJSON(&buf, f.Key); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := encodeJSON(&buf, f.Value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o jsonObject) index(key string) int {
+	for i, f := range o {
+		if f.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// encodeJSON writes v to buf without the trailing newline. HTML characters aren't escaped like slog.JSONHandler.
+func encodeJSON(buf *bytes.Buffer, v any) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// marshalEntry returns the line of entry.
+func marshalEntry(entry jsonObject) []byte {
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, entry); err != nil {
+		return nil
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func decodeEntry(p []byte) (jsonObject, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	v, err := decodeJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := v.(jsonObject)
+	if !ok {
+		return nil, fmt.Errorf("entry isn't JSON object: %s", p)
+	}
+	return entry, nil
+}
+
+func decodeJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := jsonObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonField{Key: key.(string), Value: v})
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []any{}
+		for dec.More() {
+			v, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// truncateEntry truncates the longest string of entry until the line of entry is at most maxSize bytes.
+// The special fields of Cloud Logging, except for the message and the stack trace, aren't truncated.
+func truncateEntry(entry jsonObject, maxSize int) []byte {
+	for {
+		b := marshalEntry(entry)
+		excess := len(b) - maxSize
+		if excess <= 0 {
+			return b
+		}
+
+		s := longestString(entry)
+		if s == nil {
+			return b
+		}
+		str := (*s).(string)
+		if i := entry.index(StackTraceKey); i >= 0 && s == &entry[i].Value {
+			// The message at the head of the stack trace is truncated before the frames.
+			if truncated, ok := truncateStackMessage(str, excess); ok {
+				*s = truncated
+				continue
+			}
+		}
+		truncated := truncateEscaped(strings.TrimSuffix(str, truncatedMarker), max(escapedLen(str)-excess-len(truncatedMarker), 0)) + truncatedMarker
+		if len(truncated) >= len(str) {
+			return b
+		}
+		*s = truncated
+	}
+}
+
+// truncateStackMessage truncates the message at the head of the stack trace written by formatStack by excess bytes,
+// and reports whether it is truncated.
+func truncateStackMessage(stack string, excess int) (string, bool) {
+	i := strings.LastIndex(stack, "\n\ngoroutine ")
+	if i < 0 {
+		return "", false
+	}
+	msg, frames := stack[:i], stack[i:]
+	truncated := truncateEscaped(strings.TrimSuffix(msg, truncatedMarker), max(escapedLen(msg)-excess-len(truncatedMarker), 0)) + truncatedMarker
+	if len(truncated) >= len(msg) {
+		return "", false
+	}
+	return truncated + frames, true
+}
+
+// longestString returns the pointer to the longest string which can be truncated in entry.
+func longestString(entry jsonObject) *any {
+	var (
+		longest *any
+		n       int
+	)
+	var walk func(v *any)
+	walk = func(v *any) {
+		switch vv := (*v).(type) {
+		case string:
+			if len(vv) > n {
+				longest, n = v, len(vv)
+			}
+		case jsonObject:
+			for i := range vv {
+				walk(&vv[i].Value)
+			}
+		case []any:
+			for i := range vv {
+				walk(&vv[i])
+			}
+		}
+	}
+	for i, f := range entry {
+		if f.Key == "time" || f.Key != MessageKey && f.Key != StackTraceKey && isCloudLoggingKey(nil, f.Key) {
+			continue
+		}
+		walk(&entry[i].Value)
+	}
+	return longest
+}
+
+// splitEntry splits the message of entry into the lines at most maxSize bytes.
+// If entry doesn't have the message, it is truncated.
+func splitEntry(entry jsonObject, maxSize int) [][]byte {
+	i := entry.index(MessageKey)
+	if i < 0 {
+		return [][]byte{truncateEntry(entry, maxSize)}
+	}
+	message, ok := entry[i].Value.(string)
+	if !ok {
+		return [][]byte{truncateEntry(entry, maxSize)}
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	uid := hex.EncodeToString(b)
+
+	// base is the entry without the message. The indexes are the largest possible to measure the size.
+	base := append(jsonObject{}, entry...)
+	base[i].Value = ""
+	base = append(base, jsonField{Key: SplitKey, Value: LogSplit{UID: uid, Index: len(message), TotalSplits: len(message)}})
+	insertID := base.index(InsertIDKey)
+	if insertID >= 0 {
+		base[insertID].Value = fmt.Sprintf("%v-%d", base[insertID].Value, len(message))
+	}
+	// Leave at least half of maxSize for the message.
+	baseSize := len(truncateEntry(base, maxSize/2))
+	if baseSize > maxSize/2 {
+		return [][]byte{truncateEntry(entry, maxSize)}
+	}
+
+	chunks := splitString(message, maxSize-baseSize)
+	lines := make([][]byte, 0, len(chunks))
+	for index, chunk := range chunks {
+		e := append(jsonObject{}, base...)
+		e[i].Value = chunk
+		e[len(e)-1].Value = LogSplit{UID: uid, Index: index, TotalSplits: len(chunks)}
+		if insertID >= 0 {
+			// Each entry must have the unique insertId, otherwise Cloud Logging removes them as duplicates.
+			e[insertID].Value = fmt.Sprintf("%v-%d", entry[insertID].Value, index)
+		}
+		if index > 0 {
+			e = withoutErrorReporting(e)
+		}
+		lines = append(lines, truncateEntry(e, maxSize))
+	}
+	return lines
+}
+
+// withoutErrorReporting returns entry without the fields of Error Reporting,
+// so that Error Reporting picks up only the first one of the split entries.
+func withoutErrorReporting(entry jsonObject) jsonObject {
+	reported := false
+	if i := entry.index(ErrorReportingTypeKey); i >= 0 {
+		reported = entry[i].Value == ErrorReportingType
+	}
+	return slices.DeleteFunc(entry, func(f jsonField) bool {
+		switch f.Key {
+		case StackTraceKey:
+			return true
+		case ErrorReportingTypeKey, ServiceContextKey, ErrorContextKey:
+			return reported
+		}
+		return false
+	})
+}
+
+// splitString splits s into the strings whose JSON escaped length is at most n bytes without splitting a rune.
+func splitString(s string, n int) []string {
+	n = max(n, len(`\u0000`))
+
+	var (
+		chunks []string
+		start  int
+		size   int
+	)
+	for i, r := range s {
+		l := escapedRuneLen(r)
+		if size+l > n {
+			chunks = append(chunks, s[start:i])
+			start, size = i, 0
+		}
+		size += l
+	}
+	return append(chunks, s[start:])
+}
+
+// truncateEscaped returns the longest prefix of s whose JSON escaped length is at most n bytes.
+func truncateEscaped(s string, n int) string {
+	size := 0
+	for i, r := range s {
+		size += escapedRuneLen(r)
+		if size > n {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func escapedLen(s string) int {
+	n := 0
+	for _, r := range s {
+		n += escapedRuneLen(r)
+	}
+	return n
+}
+
+// escapedRuneLen returns the length of r escaped by encoding/json.
+// The invalid UTF-8 byte is decoded as utf8.RuneError and escaped as \ufffd.
+func escapedRuneLen(r rune) int {
+	switch {
+	case r == '"' || r == '\\' || r == '\n' || r == '\r' || r == '\t':
+		return 2
+	case r < 0x20 || r == '\u2028' || r == '\u2029' || r == utf8.RuneError:
+		return len(`\u0000`)
+	}
+	return utf8.RuneLen(r)
+}
@@ -0,0 +1,162 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestOversizePolicy_Truncate(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		OversizePolicy: slogdriver.OversizeTruncate,
+		MaxEntrySize:   1024,
+	})
+	logger.Error("failed", slog.String("dump", strings.Repeat("\"a\"\n", 1000)), slog.Group(slogdriver.LabelKey, slog.String("label", "value")))
+
+	if buf.Len() > 1024 {
+		t.Errorf("entry should be at most 1024 bytes, got %d", buf.Len())
+	}
+
+	var got struct {
+		Severity string            `json:"severity"`
+		Message  string            `json:"message"`
+		Dump     string            `json:"dump"`
+		Labels   map[string]string `json:"logging.googleapis.com/labels"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got.Severity != "ERROR" || got.Message != "failed" || got.Labels["label"] != "value" {
+		t.Errorf("the other fields should be kept, got %+v", got)
+	}
+	if !strings.HasPrefix(got.Dump, `"a"`) || !strings.HasSuffix(got.Dump, "...(truncated)") {
+		t.Errorf("dump should be truncated with the marker, got %q", got.Dump)
+	}
+}
+
+func TestOversizePolicy_Split(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		OversizePolicy: slogdriver.OversizeSplit,
+		MaxEntrySize:   1024,
+		InsertID:       slogdriver.NewInsertIDGenerator("test"),
+	})
+	message := strings.Repeat("こんにちは \"World\"\n", 200)
+	logger.Info(message, slog.String("key", "value"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("entry should be split, got %d lines", len(lines))
+	}
+
+	var (
+		reassembled strings.Builder
+		uid         string
+		insertIDs   = map[string]struct{}{}
+	)
+	for i, line := range lines {
+		if len(line)+1 > 1024 {
+			t.Errorf("entry %d should be at most 1024 bytes, got %d", i, len(line)+1)
+		}
+
+		var got struct {
+			Message  string              `json:"message"`
+			Key      string              `json:"key"`
+			InsertID string              `json:"logging.googleapis.com/insertId"`
+			Split    slogdriver.LogSplit `json:"logging.googleapis.com/split"`
+		}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("failed to decode json: %+v", err)
+		}
+
+		if got.Key != "value" {
+			t.Errorf("entry %d should have the attributes, got key=%q", i, got.Key)
+		}
+		if got.Split.Index != i || got.Split.TotalSplits != len(lines) {
+			t.Errorf("unexpected split of entry %d: %+v", i, got.Split)
+		}
+		if i == 0 {
+			uid = got.Split.UID
+		}
+		if uid == "" || got.Split.UID != uid {
+			t.Errorf("entries should have the same uid, got %q and %q", uid, got.Split.UID)
+		}
+		insertIDs[got.InsertID] = struct{}{}
+		reassembled.WriteString(got.Message)
+	}
+
+	if reassembled.String() != message {
+		t.Error("the reassembled message should be the original message")
+	}
+	if len(insertIDs) != len(lines) {
+		t.Errorf("entries should have the unique insertId, got %v", insertIDs)
+	}
+}
+
+func TestOversizePolicy_SplitWithErrorReporting(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		OversizePolicy: slogdriver.OversizeSplit,
+		MaxEntrySize:   2000,
+		ErrorReporting: &slogdriver.ErrorReportingOptions{},
+	})
+	logger.Error(strings.Repeat("x", 3000), "error", errors.New("something wrong"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("entry should be split, got %d lines", len(lines))
+	}
+
+	for i, line := range lines {
+		if len(line)+1 > 2000 {
+			t.Errorf("entry %d should be at most 2000 bytes, got %d", i, len(line)+1)
+		}
+
+		var got map[string]any
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("failed to decode json: %+v", err)
+		}
+
+		keys := []string{slogdriver.ErrorReportingTypeKey, slogdriver.ServiceContextKey, slogdriver.ErrorContextKey, slogdriver.StackTraceKey}
+		for _, key := range keys {
+			if _, ok := got[key]; ok != (i == 0) {
+				t.Errorf("only the first entry should have key=%s, entry %d got %v", key, i, got)
+			}
+		}
+		if i > 0 {
+			continue
+		}
+
+		stackTrace, _ := got[slogdriver.StackTraceKey].(string)
+		if !strings.Contains(stackTrace, "...(truncated)\n\ngoroutine 1 [running]:\n") {
+			t.Errorf("the message of stack_trace should be truncated, got %q", stackTrace)
+		}
+		if !strings.Contains(stackTrace, "TestOversizePolicy_SplitWithErrorReporting(...)") {
+			t.Errorf("stack_trace should keep the frames, got %q", stackTrace)
+		}
+	}
+}
+
+func TestOversizePolicy_None(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{MaxEntrySize: 1024})
+	message := strings.Repeat("a", 2048)
+	logger.Info(message)
+
+	var got struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+	if got.Message != message {
+		t.Error("entry should be written as it is")
+	}
+}
@@ -0,0 +1,125 @@
+package slogdriver
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+)
+
+const (
+	ErrorReportingTypeKey = "@type"
+	ServiceContextKey     = "serviceContext"
+	StackTraceKey         = "stack_trace"
+	ErrorContextKey       = "context"
+
+	ErrorReportingType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+)
+
+// ErrorReportingOptions configures the integration with Cloud Error Reporting.
+// https://cloud.google.com/error-reporting/docs/formatting-error-messages
+type ErrorReportingOptions struct {
+	// Level is the minimum level of the records reported to Error Reporting.
+	// If Level is nil, LevelError is used.
+	Level slog.Leveler
+
+	// ServiceContext is the service which reported the error.
+	// If ServiceContext.Service is empty, DetectServiceContext is used.
+	ServiceContext ServiceContext
+}
+
+// ErrorReportLocation is the location in the source code where the error was reported.
+type ErrorReportLocation struct {
+	FilePath     string `json:"filePath"`
+	LineNumber   int    `json:"lineNumber"`
+	FunctionName string `json:"functionName"`
+}
+
+func (o *ErrorReportingOptions) level() slog.Level {
+	if o.Level == nil {
+		return LevelError
+	}
+	return o.Level.Level()
+}
+
+// handleErrorReporting adds the fields which make Error Reporting pick up the record, and reports whether the stack trace is added.
+// The fields whose keys are in userKeys aren't added, not to duplicate the keys.
+// If errStack is nil, the stack of the log statement is used,
+// so it must be called synchronously from Handle.
+func (c *cloudLoggingHandler) handleErrorReporting(r slog.Record, newRecord *slog.Record, err error, errStack []uintptr, userKeys map[string]struct{}) bool {
+	o := c.opts.ErrorReporting
+	if o == nil || err == nil || r.Level < o.level() {
+		return false
+	}
+
+	stack := errStack
+	if stack == nil {
+		stack = callerStack(r.PC)
+	}
+
+	f := sourceFrame(r.PC)
+	addAbsentAttrs(newRecord, userKeys,
+		slog.String(ErrorReportingTypeKey, ErrorReportingType),
+		slog.Any(ServiceContextKey, o.ServiceContext),
+		slog.Group(ErrorContextKey, slog.Any("reportLocation", ErrorReportLocation{
+			FilePath:     f.File,
+			LineNumber:   f.Line,
+			FunctionName: f.Function,
+		})),
+	)
+	return addAbsentAttrs(newRecord, userKeys, slog.String(StackTraceKey, formatStack(errorMessage(r, err), stack)))
+}
+
+// errorMessage returns the first line of the stack trace.
+func errorMessage(r slog.Record, err error) string {
+	if r.Message == "" {
+		return err.Error()
+	}
+	return r.Message + ": " + err.Error()
+}
+
+// callerStack returns the stack from pc, which is a caller of the current goroutine.
+// If pc isn't found in the current stack, only pc is returned.
+func callerStack(pc uintptr) []uintptr {
+	if pc == 0 {
+		return nil
+	}
+
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	for i, p := range pcs[:n] {
+		if p == pc {
+			return pcs[i:n]
+		}
+	}
+	return []uintptr{pc}
+}
+
+// formatStack formats msg and pcs like a Go panic dump, which Error Reporting can parse.
+func formatStack(msg string, pcs []uintptr) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	b.WriteString("\n\ngoroutine 1 [running]:\n")
+	if len(pcs) == 0 {
+		return b.String()
+	}
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d +0x%x\n", f.Function, f.File, f.Line, f.PC-f.Entry)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// attrError returns the error if the value of a is an error.
+func attrError(a slog.Attr) (error, bool) {
+	if a.Value.Kind() != slog.KindAny {
+		return nil, false
+	}
+	err, ok := a.Value.Any().(error)
+	return err, ok
+}
@@ -0,0 +1,183 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestCloudLoggingHandler_HandleErrorReporting(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		ErrorReporting: &slogdriver.ErrorReportingOptions{
+			ServiceContext: slogdriver.ServiceContext{Service: "test-service", Version: "v1"},
+		},
+	})
+
+	logger.Error("failed to do something", "error", errors.New("something wrong"))
+
+	var got struct {
+		Type           string                    `json:"@type"`
+		ServiceContext slogdriver.ServiceContext `json:"serviceContext"`
+		StackTrace     string                    `json:"stack_trace"`
+		Context        struct {
+			ReportLocation slogdriver.ErrorReportLocation `json:"reportLocation"`
+		} `json:"context"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got.Type != slogdriver.ErrorReportingType {
+		t.Errorf("unexpected @type: %s", got.Type)
+	}
+
+	expectedServiceContext := slogdriver.ServiceContext{Service: "test-service", Version: "v1"}
+	if got.ServiceContext != expectedServiceContext {
+		t.Errorf("serviceContext expected %+v, got %+v", expectedServiceContext, got.ServiceContext)
+	}
+
+	if !strings.HasPrefix(got.StackTrace, "failed to do something: something wrong\n\ngoroutine 1 [running]:\n") {
+		t.Errorf("stack_trace should start with message and goroutine header, got %s", got.StackTrace)
+	}
+	if !strings.Contains(got.StackTrace, "TestCloudLoggingHandler_HandleErrorReporting(...)\n\t") {
+		t.Errorf("stack_trace should have the caller frame, got %s", got.StackTrace)
+	}
+	if strings.Contains(got.StackTrace, "log/slog.") {
+		t.Errorf("stack_trace should not have slog frames, got %s", got.StackTrace)
+	}
+
+	loc := got.Context.ReportLocation
+	if !strings.HasSuffix(loc.FunctionName, "TestCloudLoggingHandler_HandleErrorReporting") {
+		t.Errorf("unexpected reportLocation.functionName: %s", loc.FunctionName)
+	}
+	if !strings.HasSuffix(loc.FilePath, "errorreporting_test.go") {
+		t.Errorf("unexpected reportLocation.filePath: %s", loc.FilePath)
+	}
+	if loc.LineNumber == 0 {
+		t.Error("reportLocation.lineNumber should be set")
+	}
+}
+
+func TestCloudLoggingHandler_HandleErrorReportingShouldNotReport(t *testing.T) {
+	tests := map[string]func(logger *slog.Logger){
+		"lower level": func(logger *slog.Logger) {
+			logger.Warn("warning", "error", errors.New("something wrong"))
+		},
+		"without error": func(logger *slog.Logger) {
+			logger.Error("failed")
+		},
+	}
+
+	for n, log := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+				ErrorReporting: &slogdriver.ErrorReportingOptions{},
+			})
+			log(logger)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode json: %+v", err)
+			}
+
+			if _, ok := got[slogdriver.ErrorReportingTypeKey]; ok {
+				t.Errorf("log should not have key=%s, got %v", slogdriver.ErrorReportingTypeKey, got)
+			}
+			if _, ok := got[slogdriver.StackTraceKey]; ok {
+				t.Errorf("log should not have key=%s, got %v", slogdriver.StackTraceKey, got)
+			}
+		})
+	}
+}
+
+func TestCloudLoggingHandler_HandleErrorReportingWithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		ErrorReporting: &slogdriver.ErrorReportingOptions{},
+	})
+
+	logger.With("error", errors.New("something wrong")).Error("failed to do something")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got[slogdriver.ErrorReportingTypeKey] != slogdriver.ErrorReportingType {
+		t.Errorf("log should have key=%s, got %v", slogdriver.ErrorReportingTypeKey, got)
+	}
+	stackTrace, _ := got[slogdriver.StackTraceKey].(string)
+	if !strings.HasPrefix(stackTrace, "failed to do something: something wrong\n\n") {
+		t.Errorf("stack_trace should start with the error of With, got %s", stackTrace)
+	}
+}
+
+func TestCloudLoggingHandler_HandleErrorReportingShouldNotDuplicateKeys(t *testing.T) {
+	tests := map[string]struct {
+		log      func(logger *slog.Logger)
+		key      string
+		expected string
+	}{
+		"context in record": {
+			log: func(logger *slog.Logger) {
+				logger.Error("failed", "error", errors.New("something wrong"), "context", "x")
+			},
+			key:      slogdriver.ErrorContextKey,
+			expected: `"context":"x"`,
+		},
+		"serviceContext in With": {
+			log: func(logger *slog.Logger) {
+				logger.With(slogdriver.ServiceContextKey, "x").Error("failed", "error", errors.New("something wrong"))
+			},
+			key:      slogdriver.ServiceContextKey,
+			expected: `"serviceContext":"x"`,
+		},
+		"@type in record": {
+			log: func(logger *slog.Logger) {
+				logger.Error("failed", "error", errors.New("something wrong"), slogdriver.ErrorReportingTypeKey, "x")
+			},
+			key:      slogdriver.ErrorReportingTypeKey,
+			expected: `"@type":"x"`,
+		},
+		"stack_trace in record": {
+			log: func(logger *slog.Logger) {
+				logger.Error("failed", "error", errors.New("something wrong"), slogdriver.StackTraceKey, "x")
+			},
+			key:      slogdriver.StackTraceKey,
+			expected: `"stack_trace":"x"`,
+		},
+		"group name": {
+			log: func(logger *slog.Logger) {
+				logger.WithGroup("context").Error("failed", "error", errors.New("something wrong"))
+			},
+			key:      slogdriver.ErrorContextKey,
+			expected: `"context":{"error":"something wrong"}`,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+				ErrorReporting: &slogdriver.ErrorReportingOptions{},
+				AddErrorStack:  true,
+			})
+			tt.log(logger)
+
+			got := buf.String()
+			if c := strings.Count(got, `"`+tt.key+`":`); c != 1 {
+				t.Errorf("log should have key=%s once, got %s", tt.key, got)
+			}
+			if !strings.Contains(got, tt.expected) {
+				t.Errorf("log should have %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package slogdriver
+
+import (
+	"log/slog"
+	"reflect"
+)
+
+const ErrorChainKey = "error.chain"
+
+// errorStack returns the stack where the error was created.
+// It walks the chain of err and returns the stack of the deepest error which carries its own stack trace,
+// because the deepest one is the closest to the origin.
+// The supported interfaces are
+//
+//   - StackTrace() returning a slice of uintptr-based frames, such as github.com/pkg/errors and github.com/cockroachdb/errors
+//   - Stack() []uintptr
+func errorStack(err error) []uintptr {
+	if err == nil {
+		return nil
+	}
+
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		if pcs := errorStack(u.Unwrap()); pcs != nil {
+			return pcs
+		}
+	case interface{ Unwrap() []error }:
+		for _, e := range u.Unwrap() {
+			if pcs := errorStack(e); pcs != nil {
+				return pcs
+			}
+		}
+	case interface{ Cause() error }:
+		if pcs := errorStack(u.Cause()); pcs != nil {
+			return pcs
+		}
+	}
+
+	return stackOf(err)
+}
+
+// stackOf returns the stack which err itself carries.
+func stackOf(err error) []uintptr {
+	if s, ok := err.(interface{ Stack() []uintptr }); ok {
+		return s.Stack()
+	}
+
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() {
+		return nil
+	}
+	t := m.Type()
+	if t.NumIn() != 0 || t.NumOut() != 1 || t.Out(0).Kind() != reflect.Slice || t.Out(0).Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	frames := m.Call(nil)[0]
+	if frames.Len() == 0 {
+		return nil
+	}
+	pcs := make([]uintptr, frames.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(frames.Index(i).Uint())
+	}
+	return pcs
+}
+
+// errorChain returns the messages of the errors in the chain of err in depth-first order.
+func errorChain(err error) []string {
+	var chain []string
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		chain = append(chain, err.Error())
+
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				walk(e)
+			}
+		case interface{ Cause() error }:
+			walk(u.Cause())
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// handleErrorStack adds the stack where the error was created and the chain of the error.
+// stackAdded reports whether the stack trace field has been already added by handleErrorReporting.
+func (c *cloudLoggingHandler) handleErrorStack(r slog.Record, newRecord *slog.Record, err error, errStack []uintptr, stackAdded bool, userKeys map[string]struct{}) {
+	if !c.opts.AddErrorStack || err == nil {
+		return
+	}
+
+	if errStack != nil && !stackAdded {
+		addAbsentAttrs(newRecord, userKeys, slog.String(StackTraceKey, formatStack(errorMessage(r, err), errStack)))
+	}
+
+	if chain := errorChain(err); len(chain) > 1 {
+		addAbsentAttrs(newRecord, userKeys, slog.Any(ErrorChainKey, chain))
+	}
+}
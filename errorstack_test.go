@@ -0,0 +1,156 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+// frame and stackTraceError mimic github.com/pkg/errors.
+type frame uintptr
+
+type stackTraceError struct {
+	msg   string
+	stack []frame
+}
+
+func (e *stackTraceError) Error() string { return e.msg }
+
+func (e *stackTraceError) StackTrace() []frame { return e.stack }
+
+func newStackTraceError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	stack := make([]frame, n)
+	for i, pc := range pcs[:n] {
+		stack[i] = frame(pc)
+	}
+	return &stackTraceError{msg: msg, stack: stack}
+}
+
+type stackError struct {
+	msg   string
+	stack []uintptr
+}
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) Stack() []uintptr { return e.stack }
+
+func newStackError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &stackError{msg: msg, stack: pcs[:n]}
+}
+
+func createStackTraceError() error {
+	return newStackTraceError("origin")
+}
+
+func createStackError() error {
+	return newStackError("origin")
+}
+
+func TestCloudLoggingHandler_HandleErrorStack(t *testing.T) {
+	tests := map[string]struct {
+		err         error
+		expectChain []string
+	}{
+		"StackTrace": {
+			err:         fmt.Errorf("wrap: %w", createStackTraceError()),
+			expectChain: []string{"wrap: origin", "origin"},
+		},
+		"Stack": {
+			err:         fmt.Errorf("wrap: %w", createStackError()),
+			expectChain: []string{"wrap: origin", "origin"},
+		},
+		"Join": {
+			err:         errors.Join(errors.New("other"), createStackError()),
+			expectChain: []string{"other\norigin", "other", "origin"},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+				AddSource:           true,
+				AddErrorStack:       true,
+				ErrorSourceLocation: true,
+			})
+			logger.Error("failed", "error", tt.err)
+
+			var got struct {
+				StackTrace string                            `json:"stack_trace"`
+				Chain      []string                          `json:"error.chain"`
+				Source     slogdriver.LogEntrySourceLocation `json:"logging.googleapis.com/sourceLocation"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode json: %+v", err)
+			}
+
+			if !strings.HasPrefix(got.StackTrace, "failed: ") {
+				t.Errorf("stack_trace should start with the message, got %s", got.StackTrace)
+			}
+			if !strings.Contains(got.StackTrace, ".createStack") {
+				t.Errorf("stack_trace should have the origin of the error, got %s", got.StackTrace)
+			}
+
+			if !slices.Equal(got.Chain, tt.expectChain) {
+				t.Errorf("error.chain expected %v, got %v", tt.expectChain, got.Chain)
+			}
+
+			if !strings.Contains(got.Source.Function, ".createStack") {
+				t.Errorf("sourceLocation should be the origin of the error, got %s", got.Source.Function)
+			}
+		})
+	}
+}
+
+func TestCloudLoggingHandler_HandleErrorStackWithErrorReporting(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		AddErrorStack:  true,
+		ErrorReporting: &slogdriver.ErrorReportingOptions{},
+	})
+	logger.Error("failed", "error", createStackError())
+
+	if n := bytes.Count(buf.Bytes(), []byte(`"`+slogdriver.StackTraceKey+`"`)); n != 1 {
+		t.Errorf("log should have one %s key, got %d: %s", slogdriver.StackTraceKey, n, buf.String())
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	stackTrace, _ := got[slogdriver.StackTraceKey].(string)
+	if !strings.Contains(stackTrace, ".createStackError") {
+		t.Errorf("stack_trace should have the origin of the error, got %s", stackTrace)
+	}
+}
+
+func TestCloudLoggingHandler_HandleErrorStackWithoutStack(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{AddErrorStack: true})
+	logger.Error("failed", "error", errors.New("no stack"))
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if _, ok := got[slogdriver.StackTraceKey]; ok {
+		t.Errorf("log should not have key=%s, got %v", slogdriver.StackTraceKey, got)
+	}
+	if _, ok := got[slogdriver.ErrorChainKey]; ok {
+		t.Errorf("log should not have key=%s, got %v", slogdriver.ErrorChainKey, got)
+	}
+}
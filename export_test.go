@@ -0,0 +1,19 @@
+package slogdriver
+
+import (
+	"slices"
+	"testing"
+)
+
+// RestoreTraceExtractors restores the extractors registered by RegisterTraceExtractor when t finishes.
+func RestoreTraceExtractors(t testing.TB) {
+	registeredTraceExtractorsMu.Lock()
+	saved := slices.Clone(registeredTraceExtractors)
+	registeredTraceExtractorsMu.Unlock()
+
+	t.Cleanup(func() {
+		registeredTraceExtractorsMu.Lock()
+		registeredTraceExtractors = saved
+		registeredTraceExtractorsMu.Unlock()
+	})
+}
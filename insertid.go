@@ -0,0 +1,25 @@
+package slogdriver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+)
+
+// NewInsertIDGenerator returns a generator of "logging.googleapis.com/insertId" for HandlerOptions.InsertID.
+// The generated ids are prefix followed by a counter which increases monotonically in the process,
+// so that they are unique and sorted in the order of logging.
+// If prefix is empty, a random prefix is used to distinguish the processes.
+func NewInsertIDGenerator(prefix string) func() string {
+	if prefix == "" {
+		b := make([]byte, 8)
+		_, _ = rand.Read(b)
+		prefix = hex.EncodeToString(b)
+	}
+
+	var counter atomic.Uint64
+	return func() string {
+		return fmt.Sprintf("%s-%016x", prefix, counter.Add(1))
+	}
+}
@@ -0,0 +1,68 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestNewInsertIDGenerator(t *testing.T) {
+	generate := slogdriver.NewInsertIDGenerator("prefix")
+
+	first := generate()
+	second := generate()
+	if first != "prefix-0000000000000001" {
+		t.Errorf("unexpected first id: %s", first)
+	}
+	if first >= second {
+		t.Errorf("ids should increase monotonically: %s, %s", first, second)
+	}
+
+	if slogdriver.NewInsertIDGenerator("")() == slogdriver.NewInsertIDGenerator("")() {
+		t.Error("random prefixes should be different")
+	}
+}
+
+func TestCloudLoggingHandler_HandleInsertID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		InsertID: slogdriver.NewInsertIDGenerator("prefix"),
+	})
+
+	logger.Info("first")
+	logger.Info("second", slog.String(slogdriver.InsertIDKey, "specified"))
+	logger.Info("third")
+
+	dec := json.NewDecoder(&buf)
+	for _, expected := range []string{"prefix-0000000000000001", "specified", "prefix-0000000000000002"} {
+		var got map[string]any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("failed to decode json: %+v", err)
+		}
+
+		if got[slogdriver.InsertIDKey] != expected {
+			t.Errorf("insertId expected %s, got %v", expected, got[slogdriver.InsertIDKey])
+		}
+	}
+}
+
+func TestCloudLoggingHandler_HandleInsertIDWithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		InsertID: slogdriver.NewInsertIDGenerator("prefix"),
+	})
+
+	logger.With(slog.String(slogdriver.InsertIDKey, "specified")).Info("Hello World")
+
+	got := buf.String()
+	if c := strings.Count(got, `"`+slogdriver.InsertIDKey+`":`); c != 1 {
+		t.Errorf("log should have key=%s once, got %s", slogdriver.InsertIDKey, got)
+	}
+	if !strings.Contains(got, `"`+slogdriver.InsertIDKey+`":"specified"`) {
+		t.Errorf("insertId of WithAttrs should be kept, got %s", got)
+	}
+}
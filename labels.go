@@ -0,0 +1,271 @@
+package slogdriver
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The limits of the labels of a log entry.
+// https://cloud.google.com/logging/quotas#log-limits
+const (
+	maxLabelKeyLength   = 512
+	maxLabelValueLength = 64 * 1024
+)
+
+// Label returns a label attribute of key and value.
+// The value is converted to a string by the handler.
+//
+//	logger.Info("Hello World", slogdriver.Label("retries", 3))
+func Label(key string, value any) slog.Attr {
+	return slog.Group(LabelKey, slog.Any(key, value))
+}
+
+// Labels returns a label attribute of m. The labels are sorted by key.
+func Labels(m map[string]string) slog.Attr {
+	attrs := make([]any, 0, len(m))
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		attrs = append(attrs, slog.String(key, m[key]))
+	}
+	return slog.Group(LabelKey, attrs...)
+}
+
+// LabelsFrom returns a label attribute of the exported fields of the struct v or a pointer to it.
+// The key of each field is given by the "label" struct tag, or the field name if it doesn't have the tag.
+// The fields of the embedded structs are treated as the fields of v, like encoding/json.
+//
+//	type RequestLabels struct {
+//		Tenant string `label:"tenant"`
+//		UserID string `label:"user_id,omitempty"` // omitted when it is empty.
+//		Token  string `label:"-"`                 // always omitted.
+//	}
+//
+// If v isn't a struct, LabelsFrom returns the empty attribute, which is ignored.
+func LabelsFrom(v any) slog.Attr {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return slog.Attr{}
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return slog.Attr{}
+	}
+	return slog.Group(LabelKey, structLabels(rv)...)
+}
+
+func structLabels(rv reflect.Value) []any {
+	var attrs []any
+	for i := range rv.NumField() {
+		f := rv.Type().Field(i)
+		fv := rv.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("label"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				attrs = append(attrs, structLabels(fv)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+		for fv.Kind() == reflect.Pointer || fv.Kind() == reflect.Interface {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if (fv.Kind() == reflect.Pointer || fv.Kind() == reflect.Interface) && fv.IsNil() {
+			continue
+		}
+		attrs = append(attrs, slog.Attr{Key: name, Value: reflectValue(fv)})
+	}
+	return attrs
+}
+
+// reflectValue returns the slog.Value of v.
+// The fields promoted from the unexported embedded structs can't be Interface, so they are converted by their kind.
+func reflectValue(v reflect.Value) slog.Value {
+	if v.CanInterface() {
+		return slog.AnyValue(v.Interface())
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return slog.StringValue(v.String())
+	case reflect.Bool:
+		return slog.BoolValue(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return slog.Int64Value(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return slog.Uint64Value(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return slog.Float64Value(v.Float())
+	}
+	return slog.StringValue(v.String())
+}
+
+// DeleteLabel returns a label attribute which removes the label of key inherited from
+// HandlerOptions.DefaultLabels, WithAttrs or the context.
+//
+//	logger.Info("Hello World", slogdriver.DeleteLabel("commonLabel"))
+func DeleteLabel(key string) slog.Attr {
+	return slog.Group(LabelKey, slog.Any(key, deletedLabel{}))
+}
+
+type deletedLabel struct{}
+
+// normalizeLabels converts labels to the string-to-string labels of Cloud Logging.
+// The values are converted to strings, and the nested groups are flattened into dotted keys.
+// The invalid keys and values are sanitized, or dropped and reported as errors when strict is true.
+// When a key appears more than once, the last label wins at the position of the first one,
+// so labels should be ordered by ascending precedence. DeleteLabel removes the preceding label.
+func normalizeLabels(labels []slog.Attr, strict bool) ([]slog.Attr, error) {
+	result := make([]slog.Attr, 0, len(labels))
+	index := make(map[string]int, len(labels))
+	set := func(label slog.Attr) {
+		if i, ok := index[label.Key]; ok {
+			result[i] = label
+			return
+		}
+		index[label.Key] = len(result)
+		result = append(result, label)
+	}
+	remove := func(key string) {
+		if i, ok := index[key]; ok {
+			result[i] = slog.Attr{}
+			delete(index, key)
+		}
+	}
+
+	var errs []error
+	var flatten func(prefix string, attrs []slog.Attr)
+	flatten = func(prefix string, attrs []slog.Attr) {
+		for _, a := range attrs {
+			a.Value = a.Value.Resolve()
+			key := a.Key
+			if prefix != "" && key != "" {
+				key = prefix + "." + key
+			} else if prefix != "" {
+				key = prefix
+			}
+
+			if a.Value.Kind() == slog.KindGroup {
+				if a.Key == LabelKey {
+					// The label attributes, such as DeleteLabel, in DefaultLabels or the context are inlined.
+					flatten(prefix, a.Value.Group())
+					continue
+				}
+				flatten(key, a.Value.Group())
+				continue
+			}
+			if _, ok := a.Value.Any().(deletedLabel); a.Value.Kind() == slog.KindAny && ok {
+				remove(key)
+				continue
+			}
+
+			label, err := makeLabel(key, a.Value, strict)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if label.Key != "" {
+				set(label)
+			}
+		}
+	}
+	flatten("", labels)
+
+	result = slices.DeleteFunc(result, func(label slog.Attr) bool { return label.Key == "" })
+	return result, errors.Join(errs...)
+}
+
+// makeLabel returns the label of key and v. The empty key returns the empty label.
+func makeLabel(key string, v slog.Value, strict bool) (slog.Attr, error) {
+	if key == "" {
+		return slog.Attr{}, nil
+	}
+	value := labelValue(v)
+
+	if !utf8.ValidString(key) {
+		if strict {
+			return slog.Attr{}, fmt.Errorf("invalid label key %q: not valid UTF-8", key)
+		}
+		key = strings.ToValidUTF8(key, string(utf8.RuneError))
+	}
+	if len(key) > maxLabelKeyLength {
+		if strict {
+			return slog.Attr{}, fmt.Errorf("invalid label key %q: longer than %d bytes", key, maxLabelKeyLength)
+		}
+		key = truncateUTF8(key, maxLabelKeyLength)
+	}
+
+	if !utf8.ValidString(value) {
+		if strict {
+			return slog.Attr{}, fmt.Errorf("invalid label value of %q: not valid UTF-8", key)
+		}
+		value = strings.ToValidUTF8(value, string(utf8.RuneError))
+	}
+	if len(value) > maxLabelValueLength {
+		if strict {
+			return slog.Attr{}, fmt.Errorf("invalid label value of %q: longer than %d bytes", key, maxLabelValueLength)
+		}
+		value = truncateUTF8(value, maxLabelValueLength)
+	}
+
+	return slog.String(key, value), nil
+}
+
+// labelValue formats v as a label value.
+func labelValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindFloat64:
+		return strconv.FormatFloat(v.Float64(), 'g', -1, 64)
+	case slog.KindAny:
+		switch a := v.Any().(type) {
+		case encoding.TextMarshaler:
+			if b, err := a.MarshalText(); err == nil {
+				return string(b)
+			}
+		case []byte:
+			return string(a)
+		}
+	}
+	// String formats the other kinds, such as int, bool and duration, and calls fmt.Sprint for any.
+	return v.String()
+}
+
+// truncateUTF8 returns the longest prefix of s which is at most n bytes and doesn't split a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,299 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestLabels_Coercion(t *testing.T) {
+	tests := map[string]struct {
+		label  slog.Attr
+		expect map[string]string
+	}{
+		"int": {
+			label:  slog.Int("retries", 3),
+			expect: map[string]string{"retries": "3"},
+		},
+		"bool": {
+			label:  slog.Bool("canary", true),
+			expect: map[string]string{"canary": "true"},
+		},
+		"float": {
+			label:  slog.Float64("ratio", 0.5),
+			expect: map[string]string{"ratio": "0.5"},
+		},
+		"duration": {
+			label:  slog.Duration("timeout", 1500*time.Millisecond),
+			expect: map[string]string{"timeout": "1.5s"},
+		},
+		"time": {
+			label:  slog.Time("deployed", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+			expect: map[string]string{"deployed": "2024-01-02T03:04:05Z"},
+		},
+		"TextMarshaler": {
+			label:  slog.Any("ip", netip.MustParseAddr("192.0.2.1")),
+			expect: map[string]string{"ip": "192.0.2.1"},
+		},
+		"error": {
+			label:  slog.Any("err", errors.New("something wrong")),
+			expect: map[string]string{"err": "something wrong"},
+		},
+		"LogValuer": {
+			label:  slog.Any("valuer", labelValuer{}),
+			expect: map[string]string{"valuer.valuerLabel": "hoge"},
+		},
+		"nested group": {
+			label:  slog.Group("k8s", slog.String("pod", "pod-1"), slog.Group("node", slog.Int("zone", 1))),
+			expect: map[string]string{"k8s.pod": "pod-1", "k8s.node.zone": "1"},
+		},
+		"empty key": {
+			label:  slog.String("", "value"),
+			expect: nil,
+		},
+		"invalid UTF-8": {
+			label:  slog.String("key\xff", "value\xff"),
+			expect: map[string]string{"key�": "value�"},
+		},
+		"too long value": {
+			label:  slog.String("key", strings.Repeat("あ", 30000)),
+			expect: map[string]string{"key": strings.Repeat("あ", 64*1024/3)},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+			logger.Info("Hello World", slog.Group(slogdriver.LabelKey, tt.label))
+
+			var got struct {
+				Labels map[string]string `json:"logging.googleapis.com/labels"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode json: %+v", err)
+			}
+
+			if !maps.Equal(got.Labels, tt.expect) {
+				t.Errorf("labels should be %v, got %v", tt.expect, got.Labels)
+			}
+		})
+	}
+}
+
+func TestLabels_Strict(t *testing.T) {
+	var buf bytes.Buffer
+	h := slogdriver.NewHandler(&buf, slogdriver.HandlerOptions{StrictLabels: true})
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "Hello World", 0)
+	r.AddAttrs(slog.Group(slogdriver.LabelKey,
+		slog.Int("valid", 1),
+		slog.String("invalid", strings.Repeat("a", 64*1024+1)),
+	))
+	err := h.Handle(context.Background(), r)
+	if err == nil || !strings.Contains(err.Error(), `"invalid"`) {
+		t.Errorf("Handle should return the error of the invalid label, got %v", err)
+	}
+
+	var got struct {
+		Labels map[string]string `json:"logging.googleapis.com/labels"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	expect := map[string]string{"valid": "1"}
+	if !maps.Equal(got.Labels, expect) {
+		t.Errorf("the invalid label should be dropped, expected %v, got %v", expect, got.Labels)
+	}
+}
+
+func TestDeleteLabel(t *testing.T) {
+	tests := map[string]func(logger *slog.Logger){
+		"record": func(logger *slog.Logger) {
+			logger.Info("Hello World", slogdriver.DeleteLabel("with"), slogdriver.DeleteLabel("default"))
+		},
+		"WithAttrs": func(logger *slog.Logger) {
+			logger.With(slogdriver.DeleteLabel("with"), slogdriver.DeleteLabel("default")).Info("Hello World")
+		},
+		"context": func(logger *slog.Logger) {
+			ctx := slogdriver.ContextWithLabels(context.Background(), slogdriver.DeleteLabel("with"), slogdriver.DeleteLabel("default"))
+			logger.InfoContext(ctx, "Hello World")
+		},
+	}
+
+	for n, log := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+				DefaultLabels: []slog.Attr{slog.String("default", "default"), slog.String("kept", "kept")},
+			})
+			logger = logger.With(slogdriver.Label("with", "with"))
+			log(logger)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode json: %+v", err)
+			}
+
+			expect := map[string]any{"kept": "kept"}
+			if labels, _ := got[slogdriver.LabelKey].(map[string]any); !maps.Equal(labels, expect) {
+				t.Errorf("labels should be %v, got %v", expect, got[slogdriver.LabelKey])
+			}
+			if _, ok := got["with"]; ok {
+				t.Errorf("DeleteLabel should not be written to the payload, got %v", got)
+			}
+		})
+	}
+}
+
+func TestLabels_OnLabelError(t *testing.T) {
+	var buf bytes.Buffer
+	var errs []error
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		StrictLabels: true,
+		OnLabelError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+
+	logger.Info("Hello World", slogdriver.Label("invalid", strings.Repeat("a", 64*1024+1)))
+	logger.Info("Hello World", slogdriver.Label("valid", 1))
+
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `"invalid"`) {
+		t.Errorf("OnLabelError should be called once with the error of the invalid label, got %v", errs)
+	}
+}
+
+func TestLabels_Precedence(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		DefaultLabels: []slog.Attr{
+			slog.String("default", "default"),
+			slog.String("with", "default"),
+			slog.String("context", "default"),
+			slog.String("record", "default"),
+			slog.String("deleted", "default"),
+		},
+	})
+	logger = logger.With(
+		slog.Group(slogdriver.LabelKey, slog.String("with", "with"), slog.String("context", "with")),
+		slog.Group(slogdriver.LabelKey, slog.String("record", "with")),
+	)
+	ctx := slogdriver.ContextWithLabels(context.Background(), slog.String("context", "context"), slog.String("record", "context"))
+	logger.InfoContext(ctx, "Hello World",
+		slog.Group(slogdriver.LabelKey, slog.String("record", "record")),
+		slogdriver.DeleteLabel("deleted"),
+	)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	expect := map[string]any{
+		"default": "default",
+		"with":    "with",
+		"context": "context",
+		"record":  "record",
+	}
+	if labels, _ := got[slogdriver.LabelKey].(map[string]any); !maps.Equal(labels, expect) {
+		t.Errorf("labels should be %v, got %v", expect, got[slogdriver.LabelKey])
+	}
+
+	if n := strings.Count(buf.String(), `"record":`); n != 1 {
+		t.Errorf("the label key should appear once, got %d times: %s", n, buf.String())
+	}
+}
+
+type embeddedLabels struct {
+	Region string `label:"region"`
+}
+
+type EmbeddedLabels struct {
+	Zone string `label:"zone"`
+}
+
+type requestLabels struct {
+	embeddedLabels
+	*EmbeddedLabels
+	Tenant   string        `label:"tenant"`
+	UserID   string        `label:"user_id,omitempty"`
+	Token    string        `label:"-"`
+	Retries  int           `label:"retries"`
+	Timeout  time.Duration `label:"timeout"`
+	Canary   *bool         `label:"canary"`
+	Untagged string
+	internal string
+}
+
+func TestLabelHelpers(t *testing.T) {
+	tests := map[string]struct {
+		label  slog.Attr
+		expect map[string]string
+	}{
+		"Label": {
+			label:  slogdriver.Label("retries", 3),
+			expect: map[string]string{"retries": "3"},
+		},
+		"Labels": {
+			label:  slogdriver.Labels(map[string]string{"b": "2", "a": "1"}),
+			expect: map[string]string{"a": "1", "b": "2"},
+		},
+		"LabelsFrom": {
+			label: slogdriver.LabelsFrom(&requestLabels{
+				embeddedLabels: embeddedLabels{Region: "asia-northeast1"},
+				EmbeddedLabels: &EmbeddedLabels{Zone: "a"},
+				Tenant:         "tenant-1",
+				Token:          "secret",
+				Retries:        3,
+				Timeout:        time.Second,
+				Untagged:       "value",
+				internal:       "internal",
+			}),
+			expect: map[string]string{
+				"region":   "asia-northeast1",
+				"zone":     "a",
+				"tenant":   "tenant-1",
+				"retries":  "3",
+				"timeout":  "1s",
+				"Untagged": "value",
+			},
+		},
+		"LabelsFrom not struct": {
+			label:  slogdriver.LabelsFrom("value"),
+			expect: nil,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+			logger.With(tt.label).Info("Hello World")
+			logger.Info("Hello World", tt.label)
+
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var got struct {
+					Labels map[string]string `json:"logging.googleapis.com/labels"`
+				}
+				if err := json.Unmarshal([]byte(line), &got); err != nil {
+					t.Fatalf("failed to decode json: %+v", err)
+				}
+
+				if !maps.Equal(got.Labels, tt.expect) {
+					t.Errorf("labels should be %v, got %v", tt.expect, got.Labels)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package slogdriver
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// TruncatedKey is the key of the paths of the attributes which are truncated or dropped
+// by HandlerOptions.MaxAttrValueLength, MaxAttrs and MaxDepth.
+const TruncatedKey = "truncated"
+
+func (o HandlerOptions) hasAttrLimits() bool {
+	return o.MaxAttrValueLength > 0 || o.MaxAttrs > 0 || o.MaxDepth > 0
+}
+
+// attrLimiter applies the limits of HandlerOptions to the attributes, and records the paths of the truncated ones.
+type attrLimiter struct {
+	maxValueLength int
+	maxAttrs       int
+	maxDepth       int
+	truncated      []string
+}
+
+// limitAttrs applies the limits to attrs in the groups of path. depth is the number of the groups in the attribute.
+func (l *attrLimiter) limitAttrs(path []string, attrs []slog.Attr, depth int) []slog.Attr {
+	result := make([]slog.Attr, 0, len(attrs))
+	var add func(attrs []slog.Attr)
+	add = func(attrs []slog.Attr) {
+		for _, a := range attrs {
+			if a.Equal(slog.Attr{}) {
+				continue
+			}
+			a.Value = a.Value.Resolve()
+			if a.Key == "" && a.Value.Kind() == slog.KindGroup {
+				// The attributes of the inline group are at the same level, so they are counted one by one.
+				add(a.Value.Group())
+				continue
+			}
+			if l.maxAttrs > 0 && len(result) >= l.maxAttrs {
+				// The dropped attribute is recorded without limiting its value.
+				l.truncate(path, a.Key)
+				continue
+			}
+			a = l.limitAttr(path, a, depth)
+			if a.Equal(slog.Attr{}) {
+				continue
+			}
+			result = append(result, a)
+		}
+	}
+	add(attrs)
+	return result
+}
+
+func (l *attrLimiter) limitAttr(path []string, a slog.Attr, depth int) slog.Attr {
+	a.Value = a.Value.Resolve()
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		if a.Key == "" {
+			// The attributes of the inline group are at the same level.
+			a.Value = slog.GroupValue(l.limitAttrs(path, a.Value.Group(), depth)...)
+			return a
+		}
+		if l.maxDepth > 0 && depth >= l.maxDepth {
+			l.truncate(path, a.Key)
+			return slog.String(a.Key, truncatedMarker)
+		}
+		a.Value = slog.GroupValue(l.limitAttrs(append(path[:len(path):len(path)], a.Key), a.Value.Group(), depth+1)...)
+	case slog.KindString:
+		if s := a.Value.String(); l.maxValueLength > 0 && len(s) > l.maxValueLength {
+			l.truncate(path, a.Key)
+			a.Value = slog.StringValue(truncateUTF8(s, l.maxValueLength) + truncatedMarker)
+		}
+	case slog.KindAny:
+		if l.maxValueLength > 0 {
+			a.Value = l.limitAny(path, a.Key, a.Value)
+		}
+	}
+	return a
+}
+
+// limitAny limits the value in the same way as slog.JSONHandler encodes it.
+// The byte slices are truncated, and the other values longer than maxValueLength are replaced with the truncated string.
+func (l *attrLimiter) limitAny(path []string, key string, v slog.Value) slog.Value {
+	a := v.Any()
+	if b, ok := a.([]byte); ok {
+		if len(b) <= l.maxValueLength {
+			return v
+		}
+		l.truncate(path, key)
+		return slog.AnyValue(b[:l.maxValueLength])
+	}
+
+	var s string
+	if err, ok := a.(error); ok && !isJSONMarshaler(a) {
+		s = err.Error()
+	} else {
+		b, err := json.Marshal(a)
+		if err != nil {
+			return v
+		}
+		s = string(b)
+	}
+
+	if len(s) <= l.maxValueLength {
+		return v
+	}
+	l.truncate(path, key)
+	return slog.StringValue(truncateUTF8(s, l.maxValueLength) + truncatedMarker)
+}
+
+func isJSONMarshaler(v any) bool {
+	_, ok := v.(json.Marshaler)
+	return ok
+}
+
+// truncate records the path of the attribute of key. Each path is recorded only once.
+func (l *attrLimiter) truncate(path []string, key string) {
+	l.truncated = appendTruncated(l.truncated, strings.Join(append(slices.Clip(path), key), "."))
+}
+
+// appendTruncated appends the paths which aren't in truncated yet.
+func appendTruncated(truncated []string, paths ...string) []string {
+	for _, p := range paths {
+		if !slices.Contains(truncated, p) {
+			truncated = append(truncated, p)
+		}
+	}
+	return truncated
+}
+
+func groupNames(groups []group) []string {
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = g.name
+	}
+	return names
+}
@@ -0,0 +1,180 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestAttrLimits(t *testing.T) {
+	tests := map[string]struct {
+		opts            slogdriver.HandlerOptions
+		log             func(logger *slog.Logger)
+		expect          map[string]any
+		expectTruncated []any
+	}{
+		"MaxAttrValueLength": {
+			opts: slogdriver.HandlerOptions{MaxAttrValueLength: 5},
+			log: func(logger *slog.Logger) {
+				logger.Info("Hello World",
+					slog.String("sql", "SELECT * FROM users"),
+					slog.String("short", "12345"),
+					slog.Group("request", slog.String("body", "こんにちは")),
+					slog.Any("bytes", []byte("1234567")),
+					slog.Any("error", errors.New("something wrong")),
+					slog.Any("struct", struct{ Key string }{Key: "value"}),
+				)
+			},
+			expect: map[string]any{
+				"sql":     "SELEC...(truncated)",
+				"short":   "12345",
+				"request": map[string]any{"body": "こ...(truncated)"},
+				"bytes":   "MTIzNDU=",
+				"error":   "somet...(truncated)",
+				"struct":  `{"Key...(truncated)`,
+			},
+			expectTruncated: []any{"sql", "request.body", "bytes", "error", "struct"},
+		},
+		"MaxAttrs": {
+			opts: slogdriver.HandlerOptions{MaxAttrs: 2},
+			log: func(logger *slog.Logger) {
+				logger.With("common", "value").Info("Hello World",
+					slog.String("a", "1"),
+					slog.Group("g", slog.String("b", "2"), slog.String("c", "3"), slog.String("d", "4")),
+					slog.String("e", "5"),
+				)
+			},
+			expect: map[string]any{
+				"common": "value",
+				"a":      "1",
+				"g":      map[string]any{"b": "2", "c": "3"},
+			},
+			expectTruncated: []any{"g.d", "e"},
+		},
+		"MaxDepth": {
+			opts: slogdriver.HandlerOptions{MaxDepth: 1},
+			log: func(logger *slog.Logger) {
+				logger.Info("Hello World", slog.Group("g1", slog.String("a", "1"), slog.Group("g2", slog.String("b", "2"))))
+			},
+			expect: map[string]any{
+				"g1": map[string]any{"a": "1", "g2": "...(truncated)"},
+			},
+			expectTruncated: []any{"g1.g2"},
+		},
+		"WithAttrs": {
+			opts: slogdriver.HandlerOptions{MaxAttrValueLength: 5},
+			log: func(logger *slog.Logger) {
+				logger.WithGroup("g").With("sql", "SELECT * FROM users").Info("Hello World")
+			},
+			expect: map[string]any{
+				"g": map[string]any{"sql": "SELEC...(truncated)"},
+			},
+			expectTruncated: []any{"g.sql"},
+		},
+		"MaxAttrs and MaxAttrValueLength": {
+			opts: slogdriver.HandlerOptions{MaxAttrs: 1, MaxAttrValueLength: 1},
+			log: func(logger *slog.Logger) {
+				logger.Info("Hello World",
+					slog.String("a", "12"),
+					slog.Group("g", slog.String("h", "12")),
+					slog.String("c", "12"),
+				)
+			},
+			expect: map[string]any{
+				"a": "1...(truncated)",
+			},
+			expectTruncated: []any{"a", "g", "c"},
+		},
+		"MaxAttrs with inline group": {
+			opts: slogdriver.HandlerOptions{MaxAttrs: 2},
+			log: func(logger *slog.Logger) {
+				logger.Info("Hello World", slog.Group("", "a", 1, "b", 2), "c", 3, "d", 4)
+			},
+			expect: map[string]any{
+				"a": float64(1),
+				"b": float64(2),
+			},
+			expectTruncated: []any{"c", "d"},
+		},
+		"same path": {
+			opts: slogdriver.HandlerOptions{MaxAttrValueLength: 5},
+			log: func(logger *slog.Logger) {
+				logger.With("sql", "SELECT * FROM users").Info("Hello World", "sql", "SELECT * FROM users")
+			},
+			expect: map[string]any{
+				"sql": "SELEC...(truncated)",
+			},
+			expectTruncated: []any{"sql"},
+		},
+		"special fields": {
+			opts: slogdriver.HandlerOptions{MaxAttrValueLength: 5},
+			log: func(logger *slog.Logger) {
+				logger.Info("Hello World", slog.Group(slogdriver.LabelKey, slog.String("label", "long label")))
+			},
+			expect: map[string]any{
+				slogdriver.LabelKey: map[string]any{"label": "long label"},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, tt.opts)
+			tt.log(logger)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode json: %+v", err)
+			}
+			truncated := got[slogdriver.TruncatedKey]
+			for _, key := range []string{slogdriver.SeverityKey, slogdriver.MessageKey, "time", slogdriver.TruncatedKey} {
+				delete(got, key)
+			}
+
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("attributes should be %v, got %v", tt.expect, got)
+			}
+			if tt.expectTruncated == nil {
+				if truncated != nil {
+					t.Errorf("log should not have %s, got %v", slogdriver.TruncatedKey, truncated)
+				}
+				return
+			}
+			if !reflect.DeepEqual(truncated, tt.expectTruncated) {
+				t.Errorf("%s should be %v, got %v", slogdriver.TruncatedKey, tt.expectTruncated, truncated)
+			}
+		})
+	}
+}
+
+func TestAttrLimits_ShouldNotDuplicateTruncatedKey(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{MaxAttrValueLength: 5})
+	logger.Info("Hello World", slog.String("sql", "SELECT * FROM users"), slog.String(slogdriver.TruncatedKey, "user"))
+
+	got := buf.String()
+	if c := strings.Count(got, `"`+slogdriver.TruncatedKey+`":`); c != 1 {
+		t.Errorf("log should have key=%s once, got %s", slogdriver.TruncatedKey, got)
+	}
+	if !strings.Contains(got, `"truncated":"user"`) {
+		t.Errorf("the attribute of the user should be kept, got %s", got)
+	}
+}
+
+func TestAttrLimits_Console(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{Format: slogdriver.FormatConsole, MaxAttrValueLength: 5})
+	logger.Info("Hello World", slog.String("sql", "SELECT * FROM users"))
+
+	got := buf.String()
+	if !strings.Contains(got, ` sql=SELEC...(truncated)`) || !strings.Contains(got, ` truncated="[\"sql\"]"`) {
+		t.Errorf("console should show the truncated fields, got %q", got)
+	}
+}
@@ -0,0 +1,110 @@
+package slogdriver
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Logger is slog.Logger which has the methods for the severities of Cloud Logging.
+type Logger struct {
+	*slog.Logger
+}
+
+// NewLogger creates a new Logger with the handler created by NewHandler.
+func NewLogger(w io.Writer, opts HandlerOptions) *Logger {
+	return &Logger{Logger: New(w, opts)}
+}
+
+// With returns a Logger that includes the given attributes in each output operation.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{Logger: l.Logger.With(args...)}
+}
+
+// WithGroup returns a Logger that starts a group.
+func (l *Logger) WithGroup(name string) *Logger {
+	return &Logger{Logger: l.Logger.WithGroup(name)}
+}
+
+// Notice logs at LevelNotice.
+func (l *Logger) Notice(msg string, args ...any) {
+	l.log(context.Background(), LevelNotice, msg, args...)
+}
+
+// NoticeContext logs at LevelNotice with the given context.
+func (l *Logger) NoticeContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, LevelNotice, msg, args...)
+}
+
+// Critical logs at LevelCritical.
+func (l *Logger) Critical(msg string, args ...any) {
+	l.log(context.Background(), LevelCritical, msg, args...)
+}
+
+// CriticalContext logs at LevelCritical with the given context.
+func (l *Logger) CriticalContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, LevelCritical, msg, args...)
+}
+
+// Alert logs at LevelAlert.
+func (l *Logger) Alert(msg string, args ...any) {
+	l.log(context.Background(), LevelAlert, msg, args...)
+}
+
+// AlertContext logs at LevelAlert with the given context.
+func (l *Logger) AlertContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, LevelAlert, msg, args...)
+}
+
+// Emergency logs at LevelEmergency.
+func (l *Logger) Emergency(msg string, args ...any) {
+	l.log(context.Background(), LevelEmergency, msg, args...)
+}
+
+// EmergencyContext logs at LevelEmergency with the given context.
+func (l *Logger) EmergencyContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, LevelEmergency, msg, args...)
+}
+
+// Fatal logs at LevelCritical, flushes the output and calls os.Exit(1).
+func (l *Logger) Fatal(msg string, args ...any) {
+	l.log(context.Background(), LevelCritical, msg, args...)
+	_ = l.Sync()
+	os.Exit(1)
+}
+
+// FatalContext logs at LevelCritical with the given context, flushes the output and calls os.Exit(1).
+func (l *Logger) FatalContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, LevelCritical, msg, args...)
+	_ = l.Sync()
+	os.Exit(1)
+}
+
+// Sync flushes the output of the handler if it supports.
+func (l *Logger) Sync() error {
+	if s, ok := l.Handler().(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// log is the low-level logging method like slog.Logger.log.
+// It must always be called directly by an exported logging method, so that the source location is the caller of them.
+func (l *Logger) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !l.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// skip [runtime.Callers, this function, this function's caller]
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = l.Handler().Handle(ctx, r)
+}
@@ -0,0 +1,128 @@
+package slogdriver_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestLogger(t *testing.T) {
+	tests := map[string]struct {
+		log            func(logger *slogdriver.Logger)
+		expectSeverity string
+	}{
+		"Notice": {
+			log:            func(logger *slogdriver.Logger) { logger.Notice("msg") },
+			expectSeverity: "NOTICE",
+		},
+		"NoticeContext": {
+			log:            func(logger *slogdriver.Logger) { logger.NoticeContext(context.Background(), "msg") },
+			expectSeverity: "NOTICE",
+		},
+		"Critical": {
+			log:            func(logger *slogdriver.Logger) { logger.Critical("msg") },
+			expectSeverity: "CRITICAL",
+		},
+		"CriticalContext": {
+			log:            func(logger *slogdriver.Logger) { logger.CriticalContext(context.Background(), "msg") },
+			expectSeverity: "CRITICAL",
+		},
+		"Alert": {
+			log:            func(logger *slogdriver.Logger) { logger.Alert("msg") },
+			expectSeverity: "ALERT",
+		},
+		"AlertContext": {
+			log:            func(logger *slogdriver.Logger) { logger.AlertContext(context.Background(), "msg") },
+			expectSeverity: "ALERT",
+		},
+		"Emergency": {
+			log:            func(logger *slogdriver.Logger) { logger.Emergency("msg") },
+			expectSeverity: "EMERGENCY",
+		},
+		"EmergencyContext": {
+			log:            func(logger *slogdriver.Logger) { logger.EmergencyContext(context.Background(), "msg") },
+			expectSeverity: "EMERGENCY",
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.NewLogger(&buf, slogdriver.HandlerOptions{AddSource: true})
+			tt.log(logger.With("key", "value"))
+
+			var got struct {
+				Severity string                            `json:"severity"`
+				Key      string                            `json:"key"`
+				Source   slogdriver.LogEntrySourceLocation `json:"logging.googleapis.com/sourceLocation"`
+			}
+			if err := json.NewDecoder(&buf).Decode(&got); err != nil {
+				t.Fatalf("failed to decode json: %+v", err)
+			}
+
+			if got.Severity != tt.expectSeverity {
+				t.Errorf("Severity expected %s, got %s", tt.expectSeverity, got.Severity)
+			}
+			if got.Key != "value" {
+				t.Errorf("unexpected key: %s", got.Key)
+			}
+			if _, filename := filepath.Split(got.Source.File); filename != "logger_test.go" {
+				t.Errorf("filepath should be logger_test.go, got %s", filename)
+			}
+			if !strings.Contains(got.Source.Function, "TestLogger") {
+				t.Errorf("function should be TestLogger, got %s", got.Source.Function)
+			}
+		})
+	}
+}
+
+func TestLogger_Sync(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	logger := slogdriver.NewLogger(w, slogdriver.HandlerOptions{})
+	logger.Notice("msg")
+
+	if buf.Len() != 0 {
+		t.Fatalf("log should be buffered: %s", buf.String())
+	}
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() == 0 {
+		t.Error("log should be flushed")
+	}
+}
+
+func TestLogger_Fatal(t *testing.T) {
+	if os.Getenv("SLOGDRIVER_TEST_FATAL") == "1" {
+		logger := slogdriver.NewLogger(os.Stdout, slogdriver.HandlerOptions{})
+		logger.Fatal("fatal msg")
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLogger_Fatal$")
+	cmd.Env = append(os.Environ(), "SLOGDRIVER_TEST_FATAL=1")
+	out, err := cmd.Output()
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 1 {
+		t.Fatalf("process should exit with status 1, got %v", err)
+	}
+
+	var got map[string]any
+	if err := json.NewDecoder(bytes.NewReader(out)).Decode(&got); err != nil {
+		t.Fatalf("failed to decode json: %+v: %s", err, out)
+	}
+	if got[slogdriver.SeverityKey] != "CRITICAL" {
+		t.Errorf("Severity expected CRITICAL, got %s", got[slogdriver.SeverityKey])
+	}
+	if got[slogdriver.MessageKey] != "fatal msg" {
+		t.Errorf("unexpected message: %s", got[slogdriver.MessageKey])
+	}
+}
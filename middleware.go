@@ -0,0 +1,293 @@
+package slogdriver
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// MiddlewareOptions configures the request log emitted by Middleware.
+type MiddlewareOptions struct {
+	// Message is the message of the request log entry.
+	// If Message is empty, "http request" is used.
+	Message string
+
+	// StatusLevel decides the level of the request log entry from the response status code.
+	// If StatusLevel is nil, DefaultStatusLevel is used.
+	// It can be overridden per route with WithStatusLevel.
+	StatusLevel StatusLevelFunc
+
+	// EscalateLevel raises the level of the request log entry to the highest level
+	// logged with the request context during the request.
+	EscalateLevel bool
+
+	// IsGKE formats the latency for GKE instead of Cloud Run and GAE.
+	// See MakeLatency.
+	IsGKE bool
+
+	// Labels returns the labels of the request, such as the route or the tenant.
+	// They are stored to the request context by ContextWithLabels,
+	// so they are added to the request log entry and the logs of the handler.
+	Labels func(r *http.Request) []slog.Attr
+}
+
+// Middleware returns a middleware which logs one httpRequest entry per request.
+// The entry has the status code, the request and response sizes actually transferred,
+// the latency, the server IP and the protocol, and is logged with the request context
+// so that trace fields are attached.
+// The trace of traceparent or X-Cloud-Trace-Context header is stored to the request context
+// by ContextWithTrace, so it is used when no tracer SDK runs.
+//
+// The request context also has logger bound to it by BindContext, which the handler can get by FromContext.
+// The records logged by it have the trace and the labels of the request even if they are logged without context.
+func Middleware(logger *slog.Logger, opts MiddlewareOptions) func(http.Handler) http.Handler {
+	msg := opts.Message
+	if msg == "" {
+		msg = "http request"
+	}
+
+	statusLevel := opts.StatusLevel
+	if statusLevel == nil {
+		statusLevel = DefaultStatusLevel
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			state := newRequestState(statusLevel)
+			ctx := context.WithValue(r.Context(), requestStateKey{}, state)
+			if tc, ok := TraceFromHeader(r.Header); ok {
+				ctx = ContextWithTrace(ctx, tc)
+			}
+			if opts.Labels != nil {
+				ctx = ContextWithLabels(ctx, opts.Labels(r)...)
+			}
+			ctx = IntoContext(ctx, BindContext(logger, ctx))
+			r = r.WithContext(ctx)
+			body := &countingReadCloser{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			rw := &responseWriter{ResponseWriter: w}
+
+			defer func() {
+				status := rw.status()
+				v := recover()
+				if v != nil {
+					status = http.StatusInternalServerError
+				}
+
+				p := MakeHTTPPayload(r, nil)
+				p.Status = status
+				p.RequestSize = strconv.FormatInt(body.n, 10)
+				p.ResponseSize = strconv.FormatInt(rw.written, 10)
+				p.Latency = MakeLatency(time.Since(start), opts.IsGKE)
+				p.ServerIP = serverIP(r)
+				level := state.statusLevel(status)
+				if maxLevel, ok := state.maxLevel(); opts.EscalateLevel && ok && maxLevel > level {
+					level = maxLevel
+				}
+				logger.LogAttrs(r.Context(), level, msg, MakeHTTPAttrFromHTTPPayload(p))
+
+				if v != nil {
+					panic(v)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// StatusLevelFunc decides the level of the request log entry from the response status code.
+type StatusLevelFunc func(status int) slog.Level
+
+// DefaultStatusLevel returns LevelError for 5xx, LevelWarning for 4xx and LevelInfo otherwise.
+func DefaultStatusLevel(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return LevelError
+	case status >= 400:
+		return LevelWarning
+	default:
+		return LevelInfo
+	}
+}
+
+// WithStatusLevel returns a handler which overrides the StatusLevel of Middleware for the route.
+func WithStatusLevel(h http.Handler, f StatusLevelFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if state := requestStateFromContext(r.Context()); state != nil {
+			state.setStatusLevel(f)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+type requestStateKey struct{}
+
+// requestState is the state of the request shared between Middleware and the handler.
+type requestState struct {
+	statusLevelFunc atomic.Pointer[StatusLevelFunc]
+	level           atomic.Int64
+}
+
+func newRequestState(f StatusLevelFunc) *requestState {
+	s := &requestState{}
+	s.statusLevelFunc.Store(&f)
+	s.level.Store(math.MinInt64)
+	return s
+}
+
+func requestStateFromContext(ctx context.Context) *requestState {
+	s, _ := ctx.Value(requestStateKey{}).(*requestState)
+	return s
+}
+
+func (s *requestState) setStatusLevel(f StatusLevelFunc) {
+	s.statusLevelFunc.Store(&f)
+}
+
+func (s *requestState) statusLevel(status int) slog.Level {
+	return (*s.statusLevelFunc.Load())(status)
+}
+
+// observeLevel records the level of the entry logged during the request.
+func (s *requestState) observeLevel(level slog.Level) {
+	for {
+		cur := s.level.Load()
+		if int64(level) <= cur || s.level.CompareAndSwap(cur, int64(level)) {
+			return
+		}
+	}
+}
+
+// maxLevel returns the highest level logged during the request.
+func (s *requestState) maxLevel() (slog.Level, bool) {
+	l := s.level.Load()
+	if l == math.MinInt64 {
+		return 0, false
+	}
+	return slog.Level(l), true
+}
+
+// serverIP returns the local address which accepted the request.
+func serverIP(req *http.Request) string {
+	addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return ""
+	}
+
+	ip, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return ip
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// responseWriter records the status code and the number of bytes written.
+// It implements http.Flusher, http.Hijacker, http.Pusher and io.ReaderFrom
+// by delegating to the underlying http.ResponseWriter.
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	written     int64
+}
+
+var (
+	_ http.Flusher  = (*responseWriter)(nil)
+	_ http.Hijacker = (*responseWriter)(nil)
+	_ http.Pusher   = (*responseWriter)(nil)
+	_ io.ReaderFrom = (*responseWriter)(nil)
+)
+
+func (w *responseWriter) status() int {
+	if w.statusCode == 0 {
+		return http.StatusOK
+	}
+	return w.statusCode
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.statusCode = code
+		// 1xx responses except 101 Switching Protocols are informational,
+		// and the final status code will be written later.
+		w.wroteHeader = code >= 200 || code == http.StatusSwitchingProtocols
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.markWritten()
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.markWritten()
+	var (
+		n   int64
+		err error
+	)
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, src)
+	}
+	w.written += n
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	w.markWritten()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) markWritten() {
+	if !w.wroteHeader {
+		w.statusCode = http.StatusOK
+		w.wroteHeader = true
+	}
+}
+
+// writerOnly hides the io.ReaderFrom implementation to avoid the infinite recursion of io.Copy.
+type writerOnly struct {
+	io.Writer
+}
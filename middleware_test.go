@@ -0,0 +1,204 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+
+	handler := slogdriver.Middleware(logger, slogdriver.MiddlewareOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("ResponseWriter should implement http.Flusher")
+		}
+
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("Hello World"))
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	res, err := http.Post(server.URL+"/path", "text/plain", strings.NewReader("request body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	var got struct {
+		Message     string                 `json:"message"`
+		HTTPRequest slogdriver.HTTPPayload `json:"httpRequest"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got.Message != "http request" {
+		t.Errorf("unexpected message: %s", got.Message)
+	}
+
+	p := got.HTTPRequest
+	if p.RequestMethod != http.MethodPost {
+		t.Errorf("unexpected requestMethod: %s", p.RequestMethod)
+	}
+	if p.RequestURL != "/path" {
+		t.Errorf("unexpected requestUrl: %s", p.RequestURL)
+	}
+	if p.Status != http.StatusCreated {
+		t.Errorf("unexpected status: %d", p.Status)
+	}
+	if p.RequestSize != "12" {
+		t.Errorf("unexpected requestSize: %s", p.RequestSize)
+	}
+	if p.ResponseSize != "11" {
+		t.Errorf("unexpected responseSize: %s", p.ResponseSize)
+	}
+	if p.ServerIP != "127.0.0.1" {
+		t.Errorf("unexpected serverIp: %s", p.ServerIP)
+	}
+	if p.Protocol != "HTTP/1.1" {
+		t.Errorf("unexpected protocol: %s", p.Protocol)
+	}
+	if p.Latency == nil {
+		t.Error("latency should be set")
+	}
+}
+
+func TestMiddleware_DefaultStatus(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+
+	handler := slogdriver.Middleware(logger, slogdriver.MiddlewareOptions{IsGKE: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var got struct {
+		HTTPRequest slogdriver.HTTPPayload `json:"httpRequest"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got.HTTPRequest.Status != http.StatusOK {
+		t.Errorf("unexpected status: %d", got.HTTPRequest.Status)
+	}
+	if _, ok := got.HTTPRequest.Latency.(string); !ok {
+		t.Errorf("latency should be string for GKE, got %T", got.HTTPRequest.Latency)
+	}
+}
+
+func TestMiddleware_Severity(t *testing.T) {
+	tests := map[string]struct {
+		handler        http.Handler
+		opts           slogdriver.MiddlewareOptions
+		expectSeverity string
+	}{
+		"2xx": {
+			handler:        http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			expectSeverity: "INFO",
+		},
+		"4xx": {
+			handler:        http.NotFoundHandler(),
+			expectSeverity: "WARNING",
+		},
+		"5xx": {
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}),
+			expectSeverity: "ERROR",
+		},
+		"custom StatusLevel": {
+			handler: http.NotFoundHandler(),
+			opts: slogdriver.MiddlewareOptions{
+				StatusLevel: func(status int) slog.Level { return slogdriver.LevelNotice },
+			},
+			expectSeverity: "NOTICE",
+		},
+		"override per route": {
+			handler: slogdriver.WithStatusLevel(http.NotFoundHandler(), func(status int) slog.Level {
+				return slogdriver.LevelDebug
+			}),
+			expectSeverity: "DEBUG",
+		},
+		"escalate to child entry": {
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				slog.New(slogdriver.NewHandler(io.Discard, slogdriver.HandlerOptions{})).ErrorContext(r.Context(), "child")
+			}),
+			opts:           slogdriver.MiddlewareOptions{EscalateLevel: true},
+			expectSeverity: "ERROR",
+		},
+		"not escalate without EscalateLevel": {
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				slog.New(slogdriver.NewHandler(io.Discard, slogdriver.HandlerOptions{})).ErrorContext(r.Context(), "child")
+			}),
+			expectSeverity: "INFO",
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, slogdriver.HandlerOptions{Level: slogdriver.LevelDefault})
+
+			handler := slogdriver.Middleware(logger, tt.opts)(tt.handler)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode json: %+v", err)
+			}
+
+			if got[slogdriver.SeverityKey] != tt.expectSeverity {
+				t.Errorf("Severity expected %s, got %s", tt.expectSeverity, got[slogdriver.SeverityKey])
+			}
+		})
+	}
+}
+
+func TestMiddleware_FromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{ProjectID: "test-project"})
+
+	handler := slogdriver.Middleware(logger, slogdriver.MiddlewareOptions{
+		Labels: func(r *http.Request) []slog.Attr {
+			return []slog.Attr{slog.String("path", r.URL.Path)}
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slogdriver.FromContext(r.Context()).Info("in handler")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	req.Header.Set(slogdriver.CloudTraceContextHeader, "105445aa7843bc8bf206b12000100000/1;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var got struct {
+			Message string            `json:"message"`
+			Trace   string            `json:"logging.googleapis.com/trace"`
+			Labels  map[string]string `json:"logging.googleapis.com/labels"`
+		}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("failed to decode json: %+v", err)
+		}
+
+		if got.Trace != "projects/test-project/traces/105445aa7843bc8bf206b12000100000" {
+			t.Errorf("%s: unexpected trace: %s", got.Message, got.Trace)
+		}
+		if got.Labels["path"] != "/path" {
+			t.Errorf("%s: unexpected labels: %v", got.Message, got.Labels)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/kitagry/slogdriver/opencensus
+
+go 1.24.0
+
+require (
+	github.com/kitagry/slogdriver v0.5.0
+	go.opencensus.io v0.24.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
+	go.opentelemetry.io/otel v1.39.0 // indirect
+	go.opentelemetry.io/otel/trace v1.39.0 // indirect
+)
+
+// v0.5.0 is the first release of the core module which has RegisterTraceExtractor.
+// The replace is for the local development only. It is ignored when this module is used as a dependency.
+replace github.com/kitagry/slogdriver => ../
@@ -0,0 +1,36 @@
+// Package opencensus integrates OpenCensus with slogdriver.
+//
+// Importing this package registers the OpenCensus span as a trace source of slogdriver.
+//
+//	import _ "github.com/kitagry/slogdriver/opencensus"
+package opencensus
+
+import (
+	"context"
+
+	"github.com/kitagry/slogdriver"
+	"go.opencensus.io/trace"
+)
+
+func init() {
+	slogdriver.RegisterTraceExtractor(TraceExtractor{})
+}
+
+// TraceExtractor extracts the trace from OpenCensus span.
+type TraceExtractor struct{}
+
+var _ slogdriver.TraceExtractor = TraceExtractor{}
+
+func (TraceExtractor) ExtractTrace(ctx context.Context) (slogdriver.TraceContext, bool) {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return slogdriver.TraceContext{}, false
+	}
+
+	spanCtx := span.SpanContext()
+	return slogdriver.TraceContext{
+		TraceID: spanCtx.TraceID.String(),
+		SpanID:  spanCtx.SpanID.String(),
+		Sampled: spanCtx.IsSampled(),
+	}, true
+}
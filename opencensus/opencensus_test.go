@@ -0,0 +1,39 @@
+package opencensus_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+	_ "github.com/kitagry/slogdriver/opencensus"
+	"go.opencensus.io/trace"
+)
+
+func TestCloudLoggingHandler_HandleTraceShouldHaveOpencensusTraceKeys(t *testing.T) {
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+
+	ctx, span := trace.StartSpan(context.Background(), "test-span")
+	defer span.End()
+
+	logger.InfoContext(ctx, "Hello World")
+
+	var got map[string]any
+	err := json.NewDecoder(&buf).Decode(&got)
+	if err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	expected := "projects/test-project/traces/" + span.SpanContext().TraceID.String()
+	if got[slogdriver.TraceKey] != expected {
+		t.Errorf("trace expected %s, got %v", expected, got[slogdriver.TraceKey])
+	}
+
+	if got[slogdriver.SpanIDKey] != span.SpanContext().SpanID.String() {
+		t.Errorf("unexpected span id: %v", got[slogdriver.SpanIDKey])
+	}
+}
@@ -0,0 +1,71 @@
+package slogdriver
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Operation is the information about an operation associated with the log entry.
+// Cloud Logging groups the entries which have the same operation id and producer.
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntryOperation
+type Operation struct {
+	ID       string `json:"id"`
+	Producer string `json:"producer,omitempty"`
+	First    bool   `json:"first,omitempty"`
+	Last     bool   `json:"last,omitempty"`
+}
+
+// MakeOperationAttr returns slog.Attr struct.
+func MakeOperationAttr(op Operation) slog.Attr {
+	return slog.Any(OperationKey, op)
+}
+
+type operationKey struct{}
+
+type operationState struct {
+	id       string
+	producer string
+	started  atomic.Bool
+}
+
+type operationContext struct {
+	*operationState
+	last bool
+}
+
+// StartOperation returns a copy of ctx which carries the operation.
+// The entries logged with the returned context have "logging.googleapis.com/operation",
+// and the first one of them is marked as the first entry of the operation.
+func StartOperation(ctx context.Context, id, producer string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operationContext{
+		operationState: &operationState{id: id, producer: producer},
+	})
+}
+
+// EndOperation returns a copy of ctx whose entries are marked as the last entry of the operation started by StartOperation.
+//
+//	logger.InfoContext(slogdriver.EndOperation(ctx), "finished")
+func EndOperation(ctx context.Context) context.Context {
+	oc, ok := ctx.Value(operationKey{}).(operationContext)
+	if !ok {
+		return ctx
+	}
+	oc.last = true
+	return context.WithValue(ctx, operationKey{}, oc)
+}
+
+// handleOperation adds the operation stored by StartOperation.
+func (c *cloudLoggingHandler) handleOperation(ctx context.Context, r *slog.Record) {
+	oc, ok := ctx.Value(operationKey{}).(operationContext)
+	if !ok {
+		return
+	}
+
+	r.AddAttrs(MakeOperationAttr(Operation{
+		ID:       oc.id,
+		Producer: oc.producer,
+		First:    oc.started.CompareAndSwap(false, true),
+		Last:     oc.last,
+	}))
+}
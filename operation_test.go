@@ -0,0 +1,88 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestMakeOperationAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+
+	logger.WithGroup("group").Info("Hello World", slogdriver.MakeOperationAttr(slogdriver.Operation{
+		ID:       "operation-id",
+		Producer: "github.com/kitagry/slogdriver",
+		First:    true,
+	}))
+
+	var got struct {
+		Operation slogdriver.Operation `json:"logging.googleapis.com/operation"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	expected := slogdriver.Operation{ID: "operation-id", Producer: "github.com/kitagry/slogdriver", First: true}
+	if got.Operation != expected {
+		t.Errorf("operation expected %+v, got %+v", expected, got.Operation)
+	}
+}
+
+func TestStartOperation(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+
+	ctx := slogdriver.StartOperation(context.Background(), "operation-id", "producer")
+	logger.InfoContext(ctx, "start")
+	logger.InfoContext(ctx, "processing")
+	logger.InfoContext(slogdriver.EndOperation(ctx), "end")
+	logger.Info("no operation")
+
+	expected := []*slogdriver.Operation{
+		{ID: "operation-id", Producer: "producer", First: true},
+		{ID: "operation-id", Producer: "producer"},
+		{ID: "operation-id", Producer: "producer", Last: true},
+		nil,
+	}
+
+	dec := json.NewDecoder(&buf)
+	for i, e := range expected {
+		var got struct {
+			Operation *slogdriver.Operation `json:"logging.googleapis.com/operation"`
+		}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("failed to decode json: %+v", err)
+		}
+
+		if e == nil {
+			if got.Operation != nil {
+				t.Errorf("entry %d should not have operation, got %+v", i, got.Operation)
+			}
+			continue
+		}
+		if got.Operation == nil || *got.Operation != *e {
+			t.Errorf("entry %d: operation expected %+v, got %+v", i, e, got.Operation)
+		}
+	}
+}
+
+func TestStartOperation_WithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+
+	ctx := slogdriver.StartOperation(context.Background(), "operation-id", "producer")
+	logger.With(slogdriver.MakeOperationAttr(slogdriver.Operation{ID: "specified"})).InfoContext(ctx, "Hello World")
+
+	got := buf.String()
+	if c := strings.Count(got, `"`+slogdriver.OperationKey+`":`); c != 1 {
+		t.Errorf("log should have key=%s once, got %s", slogdriver.OperationKey, got)
+	}
+	if !strings.Contains(got, `"id":"specified"`) {
+		t.Errorf("operation of WithAttrs should be kept, got %s", got)
+	}
+}
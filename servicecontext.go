@@ -0,0 +1,89 @@
+package slogdriver
+
+import (
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+)
+
+// ServiceContext is the service which writes the log.
+// https://cloud.google.com/error-reporting/reference/rest/v1beta1/ServiceContext
+type ServiceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+// Labels returns the service and the version as labels.
+// It can be used as HandlerOptions.DefaultLabels.
+func (sc ServiceContext) Labels() []slog.Attr {
+	labels := make([]slog.Attr, 0, 2)
+	if sc.Service != "" {
+		labels = append(labels, slog.String("service", sc.Service))
+	}
+	if sc.Version != "" {
+		labels = append(labels, slog.String("version", sc.Version))
+	}
+	return labels
+}
+
+// DetectServiceContext returns the ServiceContext of the running environment.
+// The service and the version are detected from the following in order:
+//
+//   - K_SERVICE and K_REVISION on Cloud Run
+//   - GAE_SERVICE and GAE_VERSION on App Engine
+//   - FUNCTION_TARGET on Cloud Functions
+//   - the main module path and the VCS revision of runtime/debug.ReadBuildInfo
+func DetectServiceContext() ServiceContext {
+	var sc ServiceContext
+	switch {
+	case os.Getenv("K_SERVICE") != "":
+		sc.Service = os.Getenv("K_SERVICE")
+		sc.Version = os.Getenv("K_REVISION")
+	case os.Getenv("GAE_SERVICE") != "":
+		sc.Service = os.Getenv("GAE_SERVICE")
+		sc.Version = os.Getenv("GAE_VERSION")
+	case os.Getenv("FUNCTION_TARGET") != "":
+		sc.Service = os.Getenv("FUNCTION_TARGET")
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if sc.Service == "" {
+		if ok && info.Main.Path != "" {
+			sc.Service = path.Base(info.Main.Path)
+		} else {
+			sc.Service = filepath.Base(os.Args[0])
+		}
+	}
+	if sc.Version == "" && ok {
+		sc.Version = buildVersion(info)
+	}
+	return sc
+}
+
+// buildVersion returns the VCS revision, or the module version if it isn't available.
+func buildVersion(info *debug.BuildInfo) string {
+	var revision string
+	var modified bool
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+
+	if revision != "" {
+		if modified {
+			return revision + "-dirty"
+		}
+		return revision
+	}
+
+	if info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestDetectServiceContext(t *testing.T) {
+	tests := map[string]struct {
+		env    map[string]string
+		expect slogdriver.ServiceContext
+	}{
+		"Cloud Run": {
+			env: map[string]string{
+				"K_SERVICE":  "run-service",
+				"K_REVISION": "run-service-00001-abc",
+			},
+			expect: slogdriver.ServiceContext{Service: "run-service", Version: "run-service-00001-abc"},
+		},
+		"App Engine": {
+			env: map[string]string{
+				"GAE_SERVICE": "default",
+				"GAE_VERSION": "20240101t000000",
+			},
+			expect: slogdriver.ServiceContext{Service: "default", Version: "20240101t000000"},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			for _, key := range []string{"K_SERVICE", "K_REVISION", "GAE_SERVICE", "GAE_VERSION", "FUNCTION_TARGET"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			got := slogdriver.DetectServiceContext()
+			if got != tt.expect {
+				t.Errorf("expected %+v, got %+v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestDetectServiceContext_CloudFunctions(t *testing.T) {
+	for _, key := range []string{"K_SERVICE", "K_REVISION", "GAE_SERVICE", "GAE_VERSION"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("FUNCTION_TARGET", "HelloWorld")
+
+	got := slogdriver.DetectServiceContext()
+	if got.Service != "HelloWorld" {
+		t.Errorf("unexpected service: %s", got.Service)
+	}
+}
+
+func TestDetectServiceContext_BuildInfo(t *testing.T) {
+	for _, key := range []string{"K_SERVICE", "K_REVISION", "GAE_SERVICE", "GAE_VERSION", "FUNCTION_TARGET"} {
+		t.Setenv(key, "")
+	}
+
+	got := slogdriver.DetectServiceContext()
+	if got.Service == "" {
+		t.Error("service should fall back to the build info or the executable name")
+	}
+}
+
+func TestServiceContext_Labels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		DefaultLabels: slogdriver.ServiceContext{Service: "test-service", Version: "v1"}.Labels(),
+	})
+	logger.Info("Hello World")
+
+	var got struct {
+		Labels map[string]string `json:"logging.googleapis.com/labels"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got.Labels["service"] != "test-service" || got.Labels["version"] != "v1" {
+		t.Errorf("unexpected labels: %v", got.Labels)
+	}
+}
+
+func TestCloudLoggingHandler_HandleErrorReportingShouldDetectServiceContext(t *testing.T) {
+	t.Setenv("K_SERVICE", "run-service")
+	t.Setenv("K_REVISION", "run-service-00001-abc")
+
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		ErrorReporting: &slogdriver.ErrorReportingOptions{},
+	})
+	logger.Error("failed", slog.Any("error", errors.New("something wrong")))
+
+	var got struct {
+		ServiceContext slogdriver.ServiceContext `json:"serviceContext"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	expected := slogdriver.ServiceContext{Service: "run-service", Version: "run-service-00001-abc"}
+	if got.ServiceContext != expected {
+		t.Errorf("serviceContext expected %+v, got %+v", expected, got.ServiceContext)
+	}
+}
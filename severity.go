@@ -1,6 +1,14 @@
 package slogdriver
 
-import "log/slog"
+import (
+	"encoding"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
 
 const (
 	LevelDefault   slog.Level = slog.LevelDebug - 2
@@ -14,27 +22,183 @@ const (
 	LevelEmergency slog.Level = slog.LevelError + 6
 )
 
-func levelStringToSeverity(s string) string {
-	switch s {
-	case LevelDefault.String():
-		return "DEFAULT"
-	case LevelDebug.String():
-		return "DEBUG"
-	case LevelInfo.String():
-		return "INFO"
-	case LevelNotice.String():
-		return "NOTICE"
-	case LevelWarning.String():
-		return "WARNING"
-	case LevelError.String():
-		return "ERROR"
-	case LevelCritical.String():
-		return "CRITICAL"
-	case LevelAlert.String():
-		return "ALERT"
-	case LevelEmergency.String():
+// SeverityMapper maps slog.Level to the severity of Cloud Logging.
+type SeverityMapper func(level slog.Level) string
+
+// DefaultSeverityMapper maps level to the nearest severity which is lower than or equal to level.
+// For example, slog.LevelInfo+1 is INFO, and the levels lower than LevelDefault are DEFAULT.
+func DefaultSeverityMapper(level slog.Level) string {
+	switch {
+	case level >= LevelEmergency:
 		return "EMERGENCY"
+	case level >= LevelAlert:
+		return "ALERT"
+	case level >= LevelCritical:
+		return "CRITICAL"
+	case level >= LevelError:
+		return "ERROR"
+	case level >= LevelWarning:
+		return "WARNING"
+	case level >= LevelNotice:
+		return "NOTICE"
+	case level >= LevelInfo:
+		return "INFO"
+	case level >= LevelDebug:
+		return "DEBUG"
 	default:
-		return ""
+		return "DEFAULT"
+	}
+}
+
+var severityLevels = []struct {
+	name  string
+	level slog.Level
+}{
+	{"DEFAULT", LevelDefault},
+	{"DEBUG", LevelDebug},
+	{"INFO", LevelInfo},
+	{"NOTICE", LevelNotice},
+	{"WARNING", LevelWarning},
+	{"ERROR", LevelError},
+	{"CRITICAL", LevelCritical},
+	{"ALERT", LevelAlert},
+	{"EMERGENCY", LevelEmergency},
+}
+
+// Severity is slog.Level represented by the severity names of Cloud Logging.
+// It implements encoding.TextMarshaler, encoding.TextUnmarshaler, json.Marshaler, json.Unmarshaler and flag.Value,
+// so that the level can be configured with the severity names such as NOTICE or CRITICAL.
+type Severity slog.Level
+
+var (
+	_ slog.Leveler             = Severity(0)
+	_ encoding.TextMarshaler   = Severity(0)
+	_ encoding.TextUnmarshaler = (*Severity)(nil)
+	_ json.Marshaler           = Severity(0)
+	_ json.Unmarshaler         = (*Severity)(nil)
+	_ flag.Value               = (*Severity)(nil)
+)
+
+// ParseSeverity parses the severity name case-insensitively.
+// The name can have an offset like slog.Level, e.g. "NOTICE+1".
+// "WARN" is also accepted as WARNING.
+func ParseSeverity(s string) (Severity, error) {
+	name, offset := s, 0
+	if i := strings.IndexAny(s, "+-"); i >= 0 {
+		name = s[:i]
+		var err error
+		offset, err = strconv.Atoi(s[i:])
+		if err != nil {
+			return 0, fmt.Errorf("invalid severity %q: %w", s, err)
+		}
+	}
+
+	name = strings.ToUpper(name)
+	if name == "WARN" {
+		name = "WARNING"
+	}
+	for _, sl := range severityLevels {
+		if sl.name == name {
+			return Severity(sl.level + slog.Level(offset)), nil
+		}
 	}
+	return 0, fmt.Errorf("unknown severity %q", s)
+}
+
+// Level returns s as slog.Level.
+func (s Severity) Level() slog.Level {
+	return slog.Level(s)
+}
+
+// String returns the severity name of s.
+// If s isn't one of the severity levels, it has the offset from the nearest lower severity, e.g. "NOTICE+1".
+func (s Severity) String() string {
+	level := slog.Level(s)
+	nearest := severityLevels[0]
+	for _, sl := range severityLevels {
+		if sl.level <= level {
+			nearest = sl
+		}
+	}
+
+	if level == nearest.level {
+		return nearest.name
+	}
+	return fmt.Sprintf("%s%+d", nearest.name, level-nearest.level)
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(data []byte) error {
+	parsed, err := ParseSeverity(string(data))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, s.String()), nil
+}
+
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	str, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("severity should be a string: %w", err)
+	}
+	return s.UnmarshalText([]byte(str))
+}
+
+// Set implements flag.Value.
+func (s *Severity) Set(str string) error {
+	return s.UnmarshalText([]byte(str))
+}
+
+// SeverityVar is slog.LevelVar represented by the severity names of Cloud Logging.
+// It is safe for use by multiple goroutines, and can be used as HandlerOptions.Level to change the level dynamically.
+// Unlike slog.LevelVar, Set parses the severity name to implement flag.Value, and SetLevel sets slog.Level.
+type SeverityVar struct {
+	v slog.LevelVar
+}
+
+var (
+	_ slog.Leveler             = (*SeverityVar)(nil)
+	_ encoding.TextMarshaler   = (*SeverityVar)(nil)
+	_ encoding.TextUnmarshaler = (*SeverityVar)(nil)
+	_ flag.Value               = (*SeverityVar)(nil)
+)
+
+// Level returns v's level.
+func (v *SeverityVar) Level() slog.Level {
+	return v.v.Level()
+}
+
+// SetLevel sets v's level to l.
+func (v *SeverityVar) SetLevel(l slog.Level) {
+	v.v.Set(l)
+}
+
+func (v *SeverityVar) String() string {
+	return Severity(v.Level()).String()
+}
+
+func (v *SeverityVar) MarshalText() ([]byte, error) {
+	return Severity(v.Level()).MarshalText()
+}
+
+func (v *SeverityVar) UnmarshalText(data []byte) error {
+	var s Severity
+	if err := s.UnmarshalText(data); err != nil {
+		return err
+	}
+	v.SetLevel(s.Level())
+	return nil
+}
+
+// Set implements flag.Value.
+func (v *SeverityVar) Set(str string) error {
+	return v.UnmarshalText([]byte(str))
 }
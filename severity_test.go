@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"flag"
 	"log/slog"
 	"testing"
 
@@ -74,3 +75,181 @@ func TestSeverity(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultSeverityMapper(t *testing.T) {
+	tests := map[string]struct {
+		level          slog.Level
+		expectSeverity string
+	}{
+		"lower than DEFAULT":         {level: slogdriver.LevelDefault - 10, expectSeverity: "DEFAULT"},
+		"between DEFAULT and DEBUG":  {level: slogdriver.LevelDefault + 1, expectSeverity: "DEFAULT"},
+		"INFO+1":                     {level: slog.LevelInfo + 1, expectSeverity: "INFO"},
+		"between NOTICE and WARNING": {level: slogdriver.LevelNotice + 1, expectSeverity: "NOTICE"},
+		"ERROR+1":                    {level: slog.LevelError + 1, expectSeverity: "ERROR"},
+		"higher than EMERGENCY":      {level: slogdriver.LevelEmergency + 100, expectSeverity: "EMERGENCY"},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+				Level: slogdriver.LevelDefault - 10,
+			})
+
+			logger.Log(context.Background(), tt.level, "msg")
+
+			var got map[string]any
+			err := json.NewDecoder(&buf).Decode(&got)
+			if err != nil {
+				t.Fatalf("failed to decode json: %+v", err)
+			}
+
+			if tt.expectSeverity != got[slogdriver.SeverityKey] {
+				t.Errorf("Severity expected %s, got %s", tt.expectSeverity, got[slogdriver.SeverityKey])
+			}
+		})
+	}
+}
+
+func TestSeverityMapper(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		SeverityMapper: func(level slog.Level) string {
+			if level >= slog.LevelInfo+1 {
+				return "NOTICE"
+			}
+			return slogdriver.DefaultSeverityMapper(level)
+		},
+	})
+
+	logger.Log(context.Background(), slog.LevelInfo+1, "msg")
+
+	var got map[string]any
+	err := json.NewDecoder(&buf).Decode(&got)
+	if err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got[slogdriver.SeverityKey] != "NOTICE" {
+		t.Errorf("Severity expected NOTICE, got %s", got[slogdriver.SeverityKey])
+	}
+}
+
+func TestSeverity_RoundTrip(t *testing.T) {
+	levels := []slog.Level{
+		slogdriver.LevelDefault,
+		slogdriver.LevelDebug,
+		slogdriver.LevelInfo,
+		slogdriver.LevelNotice,
+		slogdriver.LevelWarning,
+		slogdriver.LevelError,
+		slogdriver.LevelCritical,
+		slogdriver.LevelAlert,
+		slogdriver.LevelEmergency,
+		slogdriver.LevelDefault - 1,
+		slogdriver.LevelNotice + 1,
+		slogdriver.LevelEmergency + 2,
+	}
+
+	for _, level := range levels {
+		s := slogdriver.Severity(level)
+		t.Run(s.String(), func(t *testing.T) {
+			text, err := s.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fromText slogdriver.Severity
+			if err := fromText.UnmarshalText(text); err != nil {
+				t.Fatal(err)
+			}
+			if fromText != s {
+				t.Errorf("text round trip expected %s, got %s", s, fromText)
+			}
+
+			b, err := json.Marshal(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fromJSON slogdriver.Severity
+			if err := json.Unmarshal(b, &fromJSON); err != nil {
+				t.Fatal(err)
+			}
+			if fromJSON != s {
+				t.Errorf("json round trip expected %s, got %s", s, fromJSON)
+			}
+		})
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	tests := map[string]struct {
+		expect    slog.Level
+		expectErr bool
+	}{
+		"NOTICE":    {expect: slogdriver.LevelNotice},
+		"critical":  {expect: slogdriver.LevelCritical},
+		"Warn":      {expect: slogdriver.LevelWarning},
+		"INFO+1":    {expect: slogdriver.LevelInfo + 1},
+		"DEFAULT-2": {expect: slogdriver.LevelDefault - 2},
+		"FATAL":     {expectErr: true},
+		"INFO+x":    {expectErr: true},
+		"":          {expectErr: true},
+	}
+
+	for s, tt := range tests {
+		t.Run(s, func(t *testing.T) {
+			got, err := slogdriver.ParseSeverity(s)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if got.Level() != tt.expect {
+				t.Errorf("expected %s, got %s", slogdriver.Severity(tt.expect), got)
+			}
+		})
+	}
+}
+
+func TestSeverity_Flag(t *testing.T) {
+	var s slogdriver.Severity
+	var v slogdriver.SeverityVar
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&s, "severity", "")
+	fs.Var(&v, "severity-var", "")
+
+	if err := fs.Parse([]string{"-severity", "ALERT", "-severity-var", "NOTICE"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Level() != slogdriver.LevelAlert {
+		t.Errorf("unexpected severity: %s", s)
+	}
+	if v.Level() != slogdriver.LevelNotice {
+		t.Errorf("unexpected severity var: %s", v.String())
+	}
+}
+
+func TestSeverityVar(t *testing.T) {
+	var v slogdriver.SeverityVar
+	if err := v.UnmarshalText([]byte("WARNING")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{Level: &v})
+	logger.Log(context.Background(), slogdriver.LevelNotice, "msg")
+	if buf.Len() != 0 {
+		t.Errorf("NOTICE should not be logged: %s", buf.String())
+	}
+
+	v.SetLevel(slogdriver.LevelNotice)
+	logger.Log(context.Background(), slogdriver.LevelNotice, "msg")
+	if buf.Len() == 0 {
+		t.Error("NOTICE should be logged")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"slices"
 )
@@ -14,6 +15,9 @@ const (
 	HTTPKey           = "httpRequest"
 	SourceLocationKey = "logging.googleapis.com/sourceLocation"
 	LabelKey          = "logging.googleapis.com/labels"
+	OperationKey      = "logging.googleapis.com/operation"
+	InsertIDKey       = "logging.googleapis.com/insertId"
+	SplitKey          = "logging.googleapis.com/split"
 
 	TraceKey        = "logging.googleapis.com/trace"
 	SpanIDKey       = "logging.googleapis.com/spanId"
@@ -26,6 +30,9 @@ var knownKeys = map[string]struct{}{
 	HTTPKey:           {},
 	SourceLocationKey: {},
 	LabelKey:          {},
+	OperationKey:      {},
+	InsertIDKey:       {},
+	SplitKey:          {},
 	TraceKey:          {},
 	SpanIDKey:         {},
 	TraceSampledKey:   {},
@@ -36,6 +43,33 @@ type cloudLoggingHandler struct {
 	labels []slog.Attr
 	groups []group
 	opts   HandlerOptions
+	w      io.Writer
+
+	// truncated is the paths of the attributes truncated by WithAttrs.
+	truncated []string
+
+	// hasTrace is true when the trace fields are added by WithAttrs.
+	hasTrace bool
+
+	// hasInsertID is true when the insertId is added by WithAttrs.
+	hasInsertID bool
+
+	// hasOperation is true when the operation is added by WithAttrs.
+	hasOperation bool
+
+	// err is the first error added by WithAttrs.
+	err error
+
+	// userKeys is the top-level keys added by WithAttrs.
+	userKeys map[string]struct{}
+
+	// replaceAttr is HandlerOptions.ReplaceAttr, which is applied only to the attributes given by the user.
+	// It is nil when Handler is given by Wrap.
+	replaceAttr func(groups []string, a slog.Attr) slog.Attr
+
+	// wrapped is true when Handler is given by Wrap.
+	// Then, the level isn't converted by ReplaceAttr, so the handler adds "severity" to each record.
+	wrapped bool
 }
 
 type group struct {
@@ -61,17 +95,117 @@ type HandlerOptions struct {
 	// to adjust the minimum level dynamically, use a LevelVar.
 	Level slog.Leveler
 
+	// ReplaceAttr is called to rewrite each non-group attribute before it is logged, like slog.HandlerOptions.ReplaceAttr.
+	// It is called for "time" and the attributes given by the user.
+	// To keep the log entry valid for Cloud Logging, it isn't called for the fields added by the handler,
+	// such as "severity", "message", "logging.googleapis.com/trace" and the fields of Error Reporting,
+	// nor for the special fields given by the user, such as "httpRequest" and the labels.
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+
+	// Format is the output format. If Format is FormatConsole, or FormatAuto on a terminal outside of Google Cloud,
+	// the handler writes a human-readable line per record for local development.
+	// The default is FormatJSON.
+	Format Format
+
+	// SeverityMapper maps the level of each record to the severity.
+	// If SeverityMapper is nil, DefaultSeverityMapper is used.
+	SeverityMapper SeverityMapper
+
 	// DefaultLabels is a set of default labels to be added to each log entry.
 	DefaultLabels []slog.Attr
+
+	// StrictLabels reports the invalid labels.
+	// Cloud Logging labels are string-to-string. So the values of the labels are converted to strings,
+	// the nested groups in the labels are flattened into dotted keys,
+	// and the keys and the values which aren't valid UTF-8 or are too long are sanitized.
+	// When StrictLabels is true, such invalid labels are dropped instead, and Handle returns the error.
+	// slog.Logger discards the error of Handle, so use OnLabelError to know the dropped labels.
+	StrictLabels bool
+
+	// OnLabelError is called with the error of the invalid labels dropped by StrictLabels.
+	// It is called synchronously from Handle, so it must not log with the same handler.
+	OnLabelError func(err error)
+
+	// ErrorReporting makes Cloud Error Reporting pick up the records which have an error attribute,
+	// including the one added by WithAttrs.
+	// The fields of Error Reporting aren't added when the user gives the top-level attributes of the same keys.
+	// If ErrorReporting is nil, the records aren't reported.
+	ErrorReporting *ErrorReportingOptions
+
+	// When AddErrorStack is true, the handler adds the stack where the error was created as "stack_trace",
+	// and the messages of the wrapped errors as "error.chain", for the records which have an error attribute.
+	// The stack is taken from the errors carrying their own stack trace, such as the errors of github.com/pkg/errors.
+	// It is also used by ErrorReporting instead of the stack of the log statement.
+	AddErrorStack bool
+
+	// When ErrorSourceLocation is true together with AddSource and AddErrorStack,
+	// "logging.googleapis.com/sourceLocation" is the location where the error was created instead of the log statement.
+	ErrorSourceLocation bool
+
+	// InsertID generates "logging.googleapis.com/insertId" of each log entry.
+	// Cloud Logging uses it to order the entries which have the same timestamp and to remove the duplicated entries.
+	// If InsertID is nil, the entries don't have insertId unless it is added as an attribute.
+	// See NewInsertIDGenerator.
+	InsertID func() string
+
+	// OversizePolicy decides how the entries larger than MaxEntrySize are written.
+	// Cloud Logging rejects the entries larger than 256 KB. The default is OversizeNone, which writes them as they are.
+	// It isn't used by the handler created by Wrap.
+	OversizePolicy OversizePolicy
+
+	// MaxEntrySize is the maximum size of the encoded entry in bytes for OversizePolicy.
+	// If MaxEntrySize is 0, DefaultMaxEntrySize is used.
+	MaxEntrySize int
+
+	// MaxAttrValueLength, MaxAttrs and MaxDepth limit the size of the attributes to keep the log costs predictable.
+	// The paths of the truncated or dropped attributes are added to the record as "truncated", such as ["sql", "request.body"],
+	// unless the user gives the top-level attribute of the same key.
+	// The special fields of Cloud Logging and the labels aren't limited by them.
+	//
+	// MaxAttrValueLength is the maximum length in bytes of the value of each attribute.
+	// The longer strings, byte slices and the JSON encoded values of slog.Any are truncated,
+	// and the strings have "...(truncated)" at the end.
+	// If MaxAttrValueLength is 0, the values aren't truncated.
+	MaxAttrValueLength int
+
+	// MaxAttrs is the maximum number of the attributes of each record and of each group in it.
+	// The rest of them are dropped. The attributes added by WithAttrs aren't counted.
+	// If MaxAttrs is 0, the attributes aren't dropped.
+	MaxAttrs int
+
+	// MaxDepth is the maximum depth of the groups nested in each attribute.
+	// The deeper groups are replaced with "...(truncated)".
+	// If MaxDepth is 0, the groups aren't truncated.
+	MaxDepth int
+
+	// TraceExtractors extracts the trace of each log entry from the context.
+	// They are evaluated in order, and the first extracted trace is used.
+	// If TraceExtractors is nil, DefaultTraceExtractors is used.
+	TraceExtractors []TraceExtractor
 }
 
 func New(w io.Writer, opts HandlerOptions) *slog.Logger {
 	return slog.New(NewHandler(w, opts))
 }
 
+// NewHandler creates a slog.Handler which writes the records to w as the structured log of Cloud Logging.
+//
+// The handler follows the rules of slog.Handler and passes testing/slogtest,
+// except for the following Cloud Logging specific behaviors:
+//
+//   - The level and the message are written as "severity" and "message".
+//   - The special fields such as "httpRequest" and "logging.googleapis.com/trace" are always written at the top level,
+//     even if they are added in groups, so that Cloud Logging can recognize them.
+//   - The attributes of the "logging.googleapis.com/labels" group are merged with HandlerOptions.DefaultLabels,
+//     the labels added by WithAttrs and the labels in the context, and written at the top level.
+//     When the same key is given more than once, the record wins over the context, WithAttrs and DefaultLabels in this order.
 func NewHandler(w io.Writer, opts HandlerOptions) slog.Handler {
-	if projectID := os.Getenv("GOOGLE_CLOUD_PROJECT"); opts.ProjectID == "" && projectID != "" {
-		opts.ProjectID = projectID
+	opts = opts.withDefaults()
+
+	if opts.Format.resolve(w) == FormatConsole {
+		h := Wrap(newConsoleHandler(w, opts), opts).(*cloudLoggingHandler)
+		h.replaceAttr = opts.ReplaceAttr
+		return h
 	}
 
 	slogOpts := slog.HandlerOptions{
@@ -80,42 +214,129 @@ func NewHandler(w io.Writer, opts HandlerOptions) slog.Handler {
 		AddSource: false,
 		Level:     opts.Level,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			switch a.Key {
-			case slog.LevelKey:
-				val := levelStringToSeverity(a.Value.String())
-				return slog.Attr{
-					Key:   SeverityKey,
-					Value: slog.StringValue(val),
-				}
-			case slog.MessageKey:
-				if a.Value.String() == "" {
-					return slog.Attr{}
-				}
-				return slog.Attr{
-					Key:   MessageKey,
-					Value: a.Value,
+			if len(groups) == 0 {
+				switch a.Key {
+				case slog.LevelKey:
+					level, _ := a.Value.Any().(slog.Level)
+					val := opts.SeverityMapper(level)
+					return slog.Attr{
+						Key:   SeverityKey,
+						Value: slog.StringValue(val),
+					}
+				case slog.MessageKey:
+					if a.Value.String() == "" {
+						return slog.Attr{}
+					}
+					return slog.Attr{
+						Key:   MessageKey,
+						Value: a.Value,
+					}
 				}
 			}
+
+			// The other attributes are replaced in Handle and WithAttrs before the handler adds the special fields.
+			if len(groups) == 0 && a.Key == slog.TimeKey && opts.ReplaceAttr != nil {
+				return opts.ReplaceAttr(groups, a)
+			}
 			return a
 		},
 	}
 
+	var out io.Writer = w
+	if opts.OversizePolicy != OversizeNone {
+		out = &entryWriter{w: w, maxSize: opts.MaxEntrySize, policy: opts.OversizePolicy}
+	}
+
+	return &cloudLoggingHandler{
+		Handler:     slog.NewJSONHandler(out, &slogOpts),
+		opts:        opts,
+		w:           w,
+		replaceAttr: opts.ReplaceAttr,
+	}
+}
+
+// Wrap returns a slog.Handler which shapes the records for Cloud Logging and then delegates them to inner.
+// The records have "severity", the labels, the trace, the source location and the other special fields
+// as the attributes, so that the same enriched records can be written by any handler,
+// such as slog.TextHandler for local development, a test recorder, or a fan-out handler.
+//
+// HandlerOptions.Level is checked in addition to inner.Enabled.
+// HandlerOptions.ReplaceAttr isn't used, so set it to inner if necessary.
+func Wrap(inner slog.Handler, opts HandlerOptions) slog.Handler {
 	return &cloudLoggingHandler{
-		Handler: slog.NewJSONHandler(w, &slogOpts),
-		opts:    opts,
+		Handler: inner,
+		opts:    opts.withDefaults(),
+		wrapped: true,
+	}
+}
+
+// withDefaults returns a copy of o with the default values.
+func (o HandlerOptions) withDefaults() HandlerOptions {
+	if projectID := os.Getenv("GOOGLE_CLOUD_PROJECT"); o.ProjectID == "" && projectID != "" {
+		o.ProjectID = projectID
+	}
+
+	if o.ErrorReporting != nil {
+		errorReporting := *o.ErrorReporting
+		if errorReporting.ServiceContext.Service == "" {
+			errorReporting.ServiceContext = DetectServiceContext()
+		}
+		o.ErrorReporting = &errorReporting
+	}
+
+	if o.SeverityMapper == nil {
+		o.SeverityMapper = DefaultSeverityMapper
+	}
+
+	if o.MaxEntrySize <= 0 {
+		o.MaxEntrySize = DefaultMaxEntrySize
+	}
+
+	if o.TraceExtractors == nil {
+		o.TraceExtractors = DefaultTraceExtractors()
 	}
+
+	return o
 }
 
 var _ slog.Handler = (*cloudLoggingHandler)(nil)
 
+func (c *cloudLoggingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if c.wrapped && c.opts.Level != nil && level < c.opts.Level.Level() {
+		return false
+	}
+	return c.Handler.Enabled(ctx, level)
+}
+
 func (c *cloudLoggingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if state := requestStateFromContext(ctx); state != nil {
+		state.observeLevel(r.Level)
+	}
+
 	newRecord := slog.NewRecord(r.Time, r.Level, r.Message, 0)
-	labels := make([]slog.Attr, 0, len(c.opts.DefaultLabels)+len(c.labels))
+	if c.wrapped {
+		newRecord.AddAttrs(slog.String(SeverityKey, c.opts.SeverityMapper(r.Level)))
+	}
+	ctxAttrs := AttrsFromContext(ctx)
+	ctxLabels := LabelsFromContext(ctx)
+	// The labels are in ascending order of precedence, because the latter one wins in normalizeLabels.
+	labels := make([]slog.Attr, 0, len(c.opts.DefaultLabels)+len(c.labels)+len(ctxLabels))
 	labels = append(labels, c.opts.DefaultLabels...)
 	labels = append(labels, c.labels...)
+	labels = append(labels, ctxLabels...)
 	knownAttrs := make([]slog.Attr, 0, len(knownKeys))
-	normalAttrs := make([]any, 0, r.NumAttrs())
-	r.Attrs(func(a slog.Attr) bool {
+	normalAttrs := make([]any, 0, len(ctxAttrs)+r.NumAttrs())
+	var recordErr error
+	hasOperation := c.hasOperation
+	hasInsertID := c.hasInsertID
+	hasTrace := c.hasTrace
+	handleAttr := func(a slog.Attr) bool {
+		// Resolve LogValuer before inspecting the value, because it may be a label group or an error.
+		a.Value = a.Value.Resolve()
 		if a.Key == LabelKey && a.Value.Kind() == slog.KindGroup {
 			// If a is label groups, merge it with c.labels.
 			labels = append(labels, a.Value.Group()...)
@@ -124,12 +345,55 @@ func (c *cloudLoggingHandler) Handle(ctx context.Context, r slog.Record) error {
 
 		if _, ok := knownKeys[a.Key]; ok {
 			knownAttrs = append(knownAttrs, a)
+			hasOperation = hasOperation || a.Key == OperationKey
+			hasInsertID = hasInsertID || a.Key == InsertIDKey
+			hasTrace = hasTrace || isTraceKey(a.Key)
 			return true
 		}
 
 		normalAttrs = append(normalAttrs, a)
 		return true
-	})
+	}
+	// The attributes in ctx are handled in the same way as the attributes of the record.
+	for _, a := range ctxAttrs {
+		handleAttr(a)
+	}
+	r.Attrs(handleAttr)
+
+	if c.replaceAttr != nil {
+		normalAttrs = toAnySlice(replaceAttrs(c.replaceAttr, groupNames(c.groups), toAttrSlice(normalAttrs)))
+	}
+
+	// The error is found after ReplaceAttr, so that the error dropped or replaced by it isn't reported.
+	for _, a := range toAttrSlice(normalAttrs) {
+		if err, ok := attrError(a); ok {
+			recordErr = err
+			break
+		}
+	}
+	if recordErr == nil {
+		// The error added by WithAttrs is used when the record doesn't have one.
+		recordErr = c.err
+	}
+
+	truncated := slices.Clone(c.truncated)
+	if c.opts.hasAttrLimits() {
+		l := c.attrLimiter(true)
+		normalAttrs = toAnySlice(l.limitAttrs(groupNames(c.groups), toAttrSlice(normalAttrs), 0))
+		truncated = appendTruncated(truncated, l.truncated...)
+	}
+
+	// userKeys is the top-level keys given by the user. The handler doesn't add the fields which have the same keys.
+	userKeys := maps.Clone(c.userKeys)
+	if userKeys == nil {
+		userKeys = make(map[string]struct{})
+	}
+	if len(c.groups) == 0 {
+		addTopLevelKeys(userKeys, toAttrSlice(normalAttrs))
+	} else {
+		// The attributes of the user are nested in the first group.
+		userKeys[c.groups[0].name] = struct{}{}
+	}
 
 	groupedAttr := slices.Clone(normalAttrs)
 	for _, group := range slices.Backward(c.groups) {
@@ -137,6 +401,14 @@ func (c *cloudLoggingHandler) Handle(ctx context.Context, r slog.Record) error {
 	}
 	newRecord.Add(groupedAttr...)
 
+	if len(truncated) > 0 {
+		addAbsentAttrs(&newRecord, userKeys, slog.Any(TruncatedKey, truncated))
+	}
+
+	labels, labelErr := normalizeLabels(labels, c.opts.StrictLabels)
+	if labelErr != nil && c.opts.OnLabelError != nil {
+		c.opts.OnLabelError(labelErr)
+	}
 	if len(labels) > 0 {
 		newRecord.AddAttrs(slog.Group(LabelKey, toAnySlice(labels)...))
 	}
@@ -145,28 +417,74 @@ func (c *cloudLoggingHandler) Handle(ctx context.Context, r slog.Record) error {
 		newRecord.AddAttrs(knownAttrs...)
 	}
 
+	if !hasOperation {
+		c.handleOperation(ctx, &newRecord)
+	}
+
+	if !hasInsertID && c.opts.InsertID != nil {
+		newRecord.AddAttrs(slog.String(InsertIDKey, c.opts.InsertID()))
+	}
+
+	var errStack []uintptr
+	if c.opts.AddErrorStack {
+		errStack = errorStack(recordErr)
+	}
+
 	if c.opts.AddSource {
-		newRecord.AddAttrs(c.makeSourceLocationAttr(r))
+		pc := r.PC
+		if c.opts.ErrorSourceLocation && len(errStack) > 0 {
+			pc = errStack[0]
+		}
+		newRecord.AddAttrs(c.makeSourceLocationAttr(pc))
 	}
 
-	c.handleTrace(ctx, &newRecord)
+	stackAdded := c.handleErrorReporting(r, &newRecord, recordErr, errStack, userKeys)
+	c.handleErrorStack(r, &newRecord, recordErr, errStack, stackAdded, userKeys)
+
+	if !hasTrace {
+		c.handleTrace(ctx, &newRecord)
+	}
 
-	return c.Handler.Handle(ctx, newRecord)
+	if err := c.Handler.Handle(ctx, newRecord); err != nil {
+		return err
+	}
+	return labelErr
 }
 
 func (c *cloudLoggingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	var labels []slog.Attr
 	i := 0
 	groupAttrs := make([]any, 0, len(attrs))
+	var limiter *attrLimiter
+	if c.opts.hasAttrLimits() {
+		limiter = c.attrLimiter(false)
+	}
+	hasTrace := c.hasTrace
+	hasInsertID := c.hasInsertID
+	hasOperation := c.hasOperation
+	withErr := c.err
 	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
 		if a.Key == LabelKey && a.Value.Kind() == slog.KindGroup {
-			labels = make([]slog.Attr, len(a.Value.Group()))
-			for i, attr := range a.Value.Group() {
-				labels[i] = attr
-			}
+			labels = append(labels, a.Value.Group()...)
 			continue
 		}
 
+		hasTrace = hasTrace || isTraceKey(a.Key)
+		hasInsertID = hasInsertID || a.Key == InsertIDKey
+		hasOperation = hasOperation || a.Key == OperationKey
+		if _, ok := knownKeys[a.Key]; !ok {
+			if c.replaceAttr != nil {
+				a = replaceAttr(c.replaceAttr, groupNames(c.groups), a)
+			}
+			if err, ok := attrError(a); ok && withErr == nil {
+				withErr = err
+			}
+			if limiter != nil {
+				a = limiter.limitAttr(groupNames(c.groups), a, 0)
+			}
+		}
+
 		if len(c.groups) > 0 {
 			if _, ok := knownKeys[a.Key]; !ok {
 				groupAttrs = append(groupAttrs, a)
@@ -181,6 +499,20 @@ func (c *cloudLoggingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	l := c.Handler.WithAttrs(attrs)
 	h := c.clone(l)
 	h.labels = append(h.labels, labels...)
+	h.hasTrace = hasTrace
+	h.hasInsertID = hasInsertID
+	h.hasOperation = hasOperation
+	h.err = withErr
+	if len(c.groups) == 0 {
+		h.userKeys = maps.Clone(c.userKeys)
+		if h.userKeys == nil {
+			h.userKeys = make(map[string]struct{})
+		}
+		addTopLevelKeys(h.userKeys, attrs)
+	}
+	if limiter != nil {
+		h.truncated = appendTruncated(h.truncated, limiter.truncated...)
+	}
 
 	if len(groupAttrs) > 0 {
 		h.groups[len(h.groups)-1].attrs = append(h.groups[len(h.groups)-1].attrs, groupAttrs...)
@@ -198,7 +530,123 @@ func (c *cloudLoggingHandler) WithGroup(name string) slog.Handler {
 func (c *cloudLoggingHandler) clone(handler slog.Handler) *cloudLoggingHandler {
 	labels := slices.Clone(c.labels)
 	groups := slices.Clone(c.groups)
-	return &cloudLoggingHandler{handler, labels, groups, c.opts}
+	h := *c
+	h.Handler = handler
+	h.labels = labels
+	h.groups = groups
+	h.truncated = slices.Clip(c.truncated)
+	return &h
+}
+
+// attrLimiter returns the limiter of the options. The number of the attributes is limited only for the record.
+func (c *cloudLoggingHandler) attrLimiter(record bool) *attrLimiter {
+	l := &attrLimiter{
+		maxValueLength: c.opts.MaxAttrValueLength,
+		maxDepth:       c.opts.MaxDepth,
+	}
+	if record {
+		l.maxAttrs = c.opts.MaxAttrs
+	}
+	return l
+}
+
+// Sync flushes the writer if it has Sync or Flush method, such as *os.File or *bufio.Writer.
+// If the handler is created by Wrap, it calls Sync of the inner handler if exists.
+func (c *cloudLoggingHandler) Sync() error {
+	if c.wrapped {
+		if s, ok := c.Handler.(interface{ Sync() error }); ok {
+			return s.Sync()
+		}
+		return nil
+	}
+
+	switch w := c.w.(type) {
+	case interface{ Sync() error }:
+		return w.Sync()
+	case interface{ Flush() error }:
+		return w.Flush()
+	}
+	return nil
+}
+
+// replaceAttrs applies f to attrs in the groups like slog.HandlerOptions.ReplaceAttr.
+// The attributes which f replaces with the empty attribute are removed.
+func replaceAttrs(f func(groups []string, a slog.Attr) slog.Attr, groups []string, attrs []slog.Attr) []slog.Attr {
+	result := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a = replaceAttr(f, groups, a)
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		result = append(result, a)
+	}
+	return result
+}
+
+func replaceAttr(f func(groups []string, a slog.Attr) slog.Attr, groups []string, a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		a = f(groups, a)
+		a.Value = a.Value.Resolve()
+		return a
+	}
+
+	if a.Key != "" {
+		groups = append(slices.Clip(groups), a.Key)
+	}
+	a.Value = slog.GroupValue(replaceAttrs(f, groups, a.Value.Group())...)
+	return a
+}
+
+// isCloudLoggingKey reports whether the attribute is the special field of Cloud Logging or in it.
+func isCloudLoggingKey(groups []string, key string) bool {
+	if len(groups) > 0 {
+		key = groups[0]
+	}
+
+	if _, ok := knownKeys[key]; ok {
+		return true
+	}
+
+	switch key {
+	case ErrorReportingTypeKey, ServiceContextKey, StackTraceKey, ErrorContextKey, ErrorChainKey:
+		return true
+	}
+	return false
+}
+
+// addTopLevelKeys adds the keys of attrs to keys. The keys in the inline groups are also added.
+func addTopLevelKeys(keys map[string]struct{}, attrs []slog.Attr) {
+	for _, a := range attrs {
+		if a.Key == "" && a.Value.Kind() == slog.KindGroup {
+			addTopLevelKeys(keys, a.Value.Group())
+			continue
+		}
+		keys[a.Key] = struct{}{}
+	}
+}
+
+// addAbsentAttrs adds the attributes whose keys aren't in keys to r, so that the keys aren't duplicated.
+// It reports whether all of attrs are added.
+func addAbsentAttrs(r *slog.Record, keys map[string]struct{}, attrs ...slog.Attr) bool {
+	added := true
+	for _, a := range attrs {
+		if _, ok := keys[a.Key]; ok {
+			added = false
+			continue
+		}
+		keys[a.Key] = struct{}{}
+		r.AddAttrs(a)
+	}
+	return added
+}
+
+func toAttrSlice(l []any) []slog.Attr {
+	result := make([]slog.Attr, len(l))
+	for i, a := range l {
+		result[i] = a.(slog.Attr)
+	}
+	return result
 }
 
 func toAnySlice[T any](tl []T) []any {
@@ -4,10 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"maps"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+	"testing/slogtest"
+	"time"
 
 	"github.com/kitagry/slogdriver"
 	"go.opentelemetry.io/contrib/detectors/gcp"
@@ -204,3 +210,319 @@ func TestGroup(t *testing.T) {
 		t.Errorf("trace sampled key not found")
 	}
 }
+
+// parseSlogtestResults decodes the log entries, and renames severity and message to the keys of slogtest.
+func parseSlogtestResults(t *testing.T, b []byte) []map[string]any {
+	t.Helper()
+
+	var results []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for dec.More() {
+		var m map[string]any
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("failed to decode json: %+v", err)
+		}
+		if v, ok := m[slogdriver.SeverityKey]; ok {
+			m[slog.LevelKey] = v
+			delete(m, slogdriver.SeverityKey)
+		}
+		if v, ok := m[slogdriver.MessageKey]; ok {
+			m[slog.MessageKey] = v
+			delete(m, slogdriver.MessageKey)
+		}
+		results = append(results, m)
+	}
+	return results
+}
+
+func TestSlogtest(t *testing.T) {
+	tests := map[string]slogdriver.HandlerOptions{
+		"default": {},
+		"with options": {
+			ProjectID:     "test-project",
+			AddSource:     true,
+			DefaultLabels: []slog.Attr{slog.String("defaultLabel", "hoge")},
+			InsertID:      slogdriver.NewInsertIDGenerator("prefix"),
+		},
+	}
+
+	for n, opts := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			slogtest.Run(t, func(t *testing.T) slog.Handler {
+				buf.Reset()
+				return slogdriver.NewHandler(&buf, opts)
+			}, func(t *testing.T) map[string]any {
+				results := parseSlogtestResults(t, buf.Bytes())
+				if len(results) != 1 {
+					t.Fatalf("expected 1 entry, got %d: %s", len(results), buf.String())
+				}
+				return results[0]
+			})
+		})
+	}
+}
+
+func TestSlogtestTestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := slogdriver.NewHandler(&buf, slogdriver.HandlerOptions{})
+	err := slogtest.TestHandler(h, func() []map[string]any {
+		return parseSlogtestResults(t, buf.Bytes())
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+type labelValuer struct{}
+
+func (labelValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("valuerLabel", "hoge"))
+}
+
+// TestCloudLoggingSpecificKeys tests the deviations from slogtest which are documented in NewHandler.
+func TestCloudLoggingSpecificKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+
+	logger.WithGroup("group").With(slog.Any(slogdriver.LabelKey, labelValuer{})).Info(
+		"Hello World",
+		slogdriver.MakeHTTPAttrFromHTTPPayload(slogdriver.HTTPPayload{RequestMethod: "GET"}),
+		slog.Group(slogdriver.LabelKey, slog.String("label", "fuga")),
+		slog.String("key", "value"),
+	)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if _, ok := got[slogdriver.HTTPKey]; !ok {
+		t.Errorf("%s should be at the top level: %v", slogdriver.HTTPKey, got)
+	}
+
+	expectedLabels := map[string]any{"valuerLabel": "hoge", "label": "fuga"}
+	if labels, _ := got[slogdriver.LabelKey].(map[string]any); !maps.Equal(labels, expectedLabels) {
+		t.Errorf("labels should be %v at the top level, got %v", expectedLabels, got[slogdriver.LabelKey])
+	}
+
+	expectedGroup := map[string]any{"key": "value"}
+	if group, _ := got["group"].(map[string]any); !maps.Equal(group, expectedGroup) {
+		t.Errorf("group should be %v, got %v", expectedGroup, got["group"])
+	}
+}
+
+func TestReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		ProjectID: "test-project",
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch {
+			case len(groups) == 0 && a.Key == slog.TimeKey:
+				return slog.Attr{}
+			case a.Key == "password":
+				return slog.String(a.Key, "REDACTED")
+			}
+			// Try to break every other attribute.
+			return slog.String("replaced_"+a.Key, "replaced")
+		},
+	})
+
+	ctx := slogdriver.ContextWithTrace(context.Background(), slogdriver.TraceContext{TraceID: "105445aa7843bc8bf206b12000100000"})
+	logger.WithGroup("group").InfoContext(
+		ctx,
+		"Hello World",
+		slog.Group(slogdriver.LabelKey, slog.String("label", "hoge")),
+		slog.String("password", "secret"),
+		slog.String("key", "value"),
+	)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if _, ok := got[slog.TimeKey]; ok {
+		t.Errorf("time should be removed: %v", got)
+	}
+	if got[slogdriver.SeverityKey] != "INFO" {
+		t.Errorf("severity should not be replaced: %v", got)
+	}
+	if got[slogdriver.MessageKey] != "Hello World" {
+		t.Errorf("message should not be replaced: %v", got)
+	}
+	if got[slogdriver.TraceKey] != "projects/test-project/traces/105445aa7843bc8bf206b12000100000" {
+		t.Errorf("trace should not be replaced: %v", got)
+	}
+
+	expectedLabels := map[string]any{"label": "hoge"}
+	if labels, _ := got[slogdriver.LabelKey].(map[string]any); !maps.Equal(labels, expectedLabels) {
+		t.Errorf("labels should not be replaced: %v", got[slogdriver.LabelKey])
+	}
+
+	expectedGroup := map[string]any{"password": "REDACTED", "replaced_key": "replaced"}
+	if group, _ := got["group"].(map[string]any); !maps.Equal(group, expectedGroup) {
+		t.Errorf("group should be %v, got %v", expectedGroup, got["group"])
+	}
+}
+
+func TestReplaceAttr_ShouldRedactOrdinaryAttrs(t *testing.T) {
+	tests := map[string]slogdriver.HandlerOptions{
+		"json":    {},
+		"console": {Format: slogdriver.FormatConsole},
+	}
+
+	for n, opts := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+				switch a.Key {
+				case "context", slogdriver.StackTraceKey, "password":
+					return slog.String(a.Key, "REDACTED")
+				}
+				return a
+			}
+			logger := slogdriver.New(&buf, opts)
+			logger.With("context", "secret").Info("Hello World", slog.String(slogdriver.StackTraceKey, "secret"), slog.String("password", "secret"))
+
+			if got := buf.String(); strings.Contains(got, "secret") {
+				t.Errorf("all attributes should be redacted, got %s", got)
+			}
+		})
+	}
+}
+
+func TestReplaceAttr_ShouldRedactReportedError(t *testing.T) {
+	tests := map[string]func(a slog.Attr) slog.Attr{
+		"drop": func(a slog.Attr) slog.Attr {
+			return slog.Attr{}
+		},
+		"replace": func(a slog.Attr) slog.Attr {
+			return slog.Any(a.Key, errors.New("REDACTED"))
+		},
+	}
+
+	for n, replace := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+				ErrorReporting: &slogdriver.ErrorReportingOptions{},
+				AddErrorStack:  true,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == "error" {
+						return replace(a)
+					}
+					return a
+				},
+			})
+			err := fmt.Errorf("failed to login: %w", errors.New("password=hunter2"))
+			logger.Error("failed", "error", err)
+			logger.With("error", err).Error("failed")
+
+			if got := buf.String(); strings.Contains(got, "hunter2") {
+				t.Errorf("the error should be redacted, got %s", got)
+			}
+		})
+	}
+}
+
+func TestHandle_NilContext(t *testing.T) {
+	var buf bytes.Buffer
+	h := slogdriver.NewHandler(&buf, slogdriver.HandlerOptions{ProjectID: "test-project"})
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "Hello World", 0)
+	//lint:ignore SA1012 Handle should accept nil context.
+	if err := h.Handle(nil, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Hello World") {
+		t.Errorf("record should be logged, got %s", buf.String())
+	}
+}
+
+type recordHandler struct {
+	records *[]slog.Record
+	attrs   []slog.Attr
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	r = r.Clone()
+	r.AddAttrs(h.attrs...)
+	*h.records = append(*h.records, r)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordHandler{records: h.records, attrs: append(slices.Clone(h.attrs), attrs...)}
+}
+
+func (h *recordHandler) WithGroup(string) slog.Handler { return h }
+
+func TestWrap(t *testing.T) {
+	var records []slog.Record
+	logger := slog.New(slogdriver.Wrap(&recordHandler{records: &records}, slogdriver.HandlerOptions{
+		ProjectID:     "test-project",
+		Level:         slogdriver.LevelNotice,
+		DefaultLabels: []slog.Attr{slog.String("defaultLabel", "hoge")},
+	}))
+
+	ctx := slogdriver.ContextWithTrace(context.Background(), slogdriver.TraceContext{TraceID: "105445aa7843bc8bf206b12000100000"})
+	logger.InfoContext(ctx, "filtered")
+	logger.With("commonKey", "fuga").WarnContext(ctx, "Hello World", slog.String("key", "value"))
+
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	got := map[string]slog.Value{}
+	records[0].Attrs(func(a slog.Attr) bool {
+		got[a.Key] = a.Value
+		return true
+	})
+
+	if got[slogdriver.SeverityKey].String() != "WARNING" {
+		t.Errorf("unexpected severity: %v", got[slogdriver.SeverityKey])
+	}
+	if got["key"].String() != "value" {
+		t.Errorf("unexpected key: %v", got["key"])
+	}
+	if got["commonKey"].String() != "fuga" {
+		t.Errorf("unexpected commonKey: %v", got["commonKey"])
+	}
+	if got[slogdriver.TraceKey].String() != "projects/test-project/traces/105445aa7843bc8bf206b12000100000" {
+		t.Errorf("unexpected trace: %v", got[slogdriver.TraceKey])
+	}
+	labels := got[slogdriver.LabelKey]
+	if labels.Kind() != slog.KindGroup || len(labels.Group()) != 1 || labels.Group()[0].Value.String() != "hoge" {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+}
+
+func TestWrap_TextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slogdriver.Wrap(slog.NewTextHandler(&buf, nil), slogdriver.HandlerOptions{}))
+	logger.WithGroup("group").Warn("Hello World", slog.String("key", "value"), slog.Group(slogdriver.LabelKey, slog.String("label", "hoge")))
+
+	got := buf.String()
+	for _, expected := range []string{"severity=WARNING", "group.key=value", slogdriver.LabelKey + ".label=hoge"} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("log should contain %s, got %s", expected, got)
+		}
+	}
+}
+
+func TestWrap_Slogtest(t *testing.T) {
+	var buf bytes.Buffer
+	slogtest.Run(t, func(t *testing.T) slog.Handler {
+		buf.Reset()
+		return slogdriver.Wrap(slog.NewJSONHandler(&buf, nil), slogdriver.HandlerOptions{})
+	}, func(t *testing.T) map[string]any {
+		var m map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+			t.Fatalf("failed to decode json: %+v", err)
+		}
+		return m
+	})
+}
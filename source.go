@@ -12,12 +12,18 @@ type LogEntrySourceLocation struct {
 	Function string `json:"function"`
 }
 
-func (c *cloudLoggingHandler) makeSourceLocationAttr(r slog.Record) slog.Attr {
-	fs := runtime.CallersFrames([]uintptr{r.PC})
-	f, _ := fs.Next()
+func (c *cloudLoggingHandler) makeSourceLocationAttr(pc uintptr) slog.Attr {
+	f := sourceFrame(pc)
 	return slog.Any(SourceLocationKey, LogEntrySourceLocation{
 		File:     f.File,
 		Line:     fmt.Sprint(f.Line),
 		Function: f.Function,
 	})
 }
+
+// sourceFrame returns the frame of pc, such as slog.Record.PC.
+func sourceFrame(pc uintptr) runtime.Frame {
+	fs := runtime.CallersFrames([]uintptr{pc})
+	f, _ := fs.Next()
+	return f
+}
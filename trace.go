@@ -4,47 +4,96 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 
-	opencensusTrace "go.opencensus.io/trace"
 	opentelemetryTrace "go.opentelemetry.io/otel/trace"
 )
 
-func (c *cloudLoggingHandler) handleTrace(ctx context.Context, r *slog.Record) {
-	if c.opts.ProjectID == "" {
-		return
-	}
+// TraceExtractor extracts the trace of the log entry from the context.
+type TraceExtractor interface {
+	// ExtractTrace returns the trace and true if ctx has it.
+	ExtractTrace(ctx context.Context) (TraceContext, bool)
+}
 
-	if ctx == nil {
-		return
-	}
+// TraceExtractorFunc is an adapter to use ordinary functions as TraceExtractor.
+type TraceExtractorFunc func(ctx context.Context) (TraceContext, bool)
 
-	c.handleOpencensusTrace(ctx, r)
-	c.handleOpentelemetryTrace(ctx, r)
+func (f TraceExtractorFunc) ExtractTrace(ctx context.Context) (TraceContext, bool) {
+	return f(ctx)
 }
 
-func (c *cloudLoggingHandler) handleOpencensusTrace(ctx context.Context, r *slog.Record) {
-	span := opencensusTrace.FromContext(ctx)
-	if span == nil {
-		return
+// OpenTelemetryTraceExtractor extracts the trace from OpenTelemetry span.
+type OpenTelemetryTraceExtractor struct{}
+
+func (OpenTelemetryTraceExtractor) ExtractTrace(ctx context.Context) (TraceContext, bool) {
+	spanCtx := opentelemetryTrace.SpanContextFromContext(ctx)
+	if !spanCtx.HasTraceID() || !spanCtx.HasSpanID() {
+		return TraceContext{}, false
 	}
 
-	spanCtx := span.SpanContext()
-	r.AddAttrs(
-		slog.String(TraceKey, fmt.Sprintf("projects/%s/traces/%s", c.opts.ProjectID, spanCtx.TraceID.String())),
-		slog.String(SpanIDKey, spanCtx.SpanID.String()),
-		slog.Bool(TraceSampledKey, spanCtx.IsSampled()),
-	)
+	return TraceContext{
+		TraceID: spanCtx.TraceID().String(),
+		SpanID:  spanCtx.SpanID().String(),
+		Sampled: spanCtx.IsSampled(),
+	}, true
 }
 
-func (c *cloudLoggingHandler) handleOpentelemetryTrace(ctx context.Context, r *slog.Record) {
-	spanCtx := opentelemetryTrace.SpanContextFromContext(ctx)
+// HeaderTraceExtractor extracts the trace parsed from the request header and stored by ContextWithTrace.
+type HeaderTraceExtractor struct{}
 
-	if !spanCtx.HasTraceID() || !spanCtx.HasSpanID() {
+func (HeaderTraceExtractor) ExtractTrace(ctx context.Context) (TraceContext, bool) {
+	return TraceFromContext(ctx)
+}
+
+var (
+	registeredTraceExtractorsMu sync.RWMutex
+	registeredTraceExtractors   []TraceExtractor
+)
+
+// RegisterTraceExtractor registers e as a trace source of DefaultTraceExtractors.
+// It is intended to be called from init function of the packages which integrate other tracers,
+// such as github.com/kitagry/slogdriver/opencensus.
+func RegisterTraceExtractor(e TraceExtractor) {
+	registeredTraceExtractorsMu.Lock()
+	defer registeredTraceExtractorsMu.Unlock()
+	registeredTraceExtractors = append(registeredTraceExtractors, e)
+}
+
+// DefaultTraceExtractors returns the extractors used when HandlerOptions.TraceExtractors is nil.
+// They are OpenTelemetryTraceExtractor, the extractors registered by RegisterTraceExtractor and HeaderTraceExtractor in this order.
+func DefaultTraceExtractors() []TraceExtractor {
+	registeredTraceExtractorsMu.RLock()
+	defer registeredTraceExtractorsMu.RUnlock()
+
+	extractors := make([]TraceExtractor, 0, len(registeredTraceExtractors)+2)
+	extractors = append(extractors, OpenTelemetryTraceExtractor{})
+	extractors = append(extractors, registeredTraceExtractors...)
+	extractors = append(extractors, HeaderTraceExtractor{})
+	return extractors
+}
+
+// isTraceKey reports whether key is the trace field, which must not be duplicated.
+func isTraceKey(key string) bool {
+	return key == TraceKey || key == SpanIDKey || key == TraceSampledKey
+}
+
+// handleTrace adds the trace fields extracted from ctx. It must not be called when the record already has them.
+func (c *cloudLoggingHandler) handleTrace(ctx context.Context, r *slog.Record) {
+	if c.opts.ProjectID == "" {
+		return
+	}
+
+	for _, e := range c.opts.TraceExtractors {
+		tc, ok := e.ExtractTrace(ctx)
+		if !ok {
+			continue
+		}
+
+		r.AddAttrs(slog.String(TraceKey, fmt.Sprintf("projects/%s/traces/%s", c.opts.ProjectID, tc.TraceID)))
+		if tc.SpanID != "" {
+			r.AddAttrs(slog.String(SpanIDKey, tc.SpanID))
+		}
+		r.AddAttrs(slog.Bool(TraceSampledKey, tc.Sampled))
 		return
 	}
-	r.AddAttrs(
-		slog.String(TraceKey, fmt.Sprintf("projects/%s/traces/%s", c.opts.ProjectID, spanCtx.TraceID().String())),
-		slog.String(SpanIDKey, spanCtx.SpanID().String()),
-		slog.Bool(TraceSampledKey, spanCtx.IsSampled()),
-	)
 }
@@ -4,39 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/kitagry/slogdriver"
-	opencensusTrace "go.opencensus.io/trace"
 	"go.opentelemetry.io/contrib/detectors/gcp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
-func TestCloudLoggingHandler_HandleTraceShouldHaveOpencensusTraceKeys(t *testing.T) {
-	t.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
-
-	var buf bytes.Buffer
-	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
-
-	ctx, span := opencensusTrace.StartSpan(context.Background(), "test-span")
-	defer span.End()
-
-	logger.InfoContext(ctx, "Hello World")
-
-	var got map[string]any
-	err := json.NewDecoder(&buf).Decode(&got)
-	if err != nil {
-		t.Fatalf("failed to decode json: %+v", err)
-	}
-
-	_, ok := got[slogdriver.TraceKey]
-	if !ok {
-		t.Errorf("log should have key=%s, got %v", slogdriver.TraceKey, got)
-	}
-}
-
 func TestCloudLoggingHandler_HandleTraceShouldHaveOpentelemetryTraceKeys(t *testing.T) {
 	t.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
 
@@ -91,3 +69,111 @@ func TestCloudLoggingHandler_HandleTraceShouldNotHaveTraceKeys(t *testing.T) {
 		t.Errorf("log should not have key=%s, got %v", slogdriver.TraceKey, got)
 	}
 }
+
+func TestCloudLoggingHandler_HandleTraceShouldNotDuplicateTraceKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{ProjectID: "test-project"})
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	ctx := slogdriver.ContextWithTrace(context.Background(), slogdriver.TraceContext{TraceID: "105445aa7843bc8bf206b12000100000"})
+	ctx, span := tp.Tracer("test-tracer").Start(ctx, "test-span")
+	defer span.End()
+
+	logger.InfoContext(ctx, "Hello World")
+
+	if n := bytes.Count(buf.Bytes(), []byte(`"`+slogdriver.TraceKey+`"`)); n != 1 {
+		t.Errorf("log should have one %s key, got %d: %s", slogdriver.TraceKey, n, buf.String())
+	}
+
+	var got map[string]any
+	if err := json.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	expected := "projects/test-project/traces/" + span.SpanContext().TraceID().String()
+	if got[slogdriver.TraceKey] != expected {
+		t.Errorf("OpenTelemetry trace should be preferred: expected %s, got %v", expected, got[slogdriver.TraceKey])
+	}
+}
+
+func TestCloudLoggingHandler_HandleTraceWithTraceExtractors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{
+		ProjectID: "test-project",
+		TraceExtractors: []slogdriver.TraceExtractor{
+			slogdriver.TraceExtractorFunc(func(ctx context.Context) (slogdriver.TraceContext, bool) {
+				return slogdriver.TraceContext{}, false
+			}),
+			slogdriver.HeaderTraceExtractor{},
+			slogdriver.OpenTelemetryTraceExtractor{},
+		},
+	})
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	ctx := slogdriver.ContextWithTrace(context.Background(), slogdriver.TraceContext{TraceID: "105445aa7843bc8bf206b12000100000"})
+	ctx, span := tp.Tracer("test-tracer").Start(ctx, "test-span")
+	defer span.End()
+
+	logger.InfoContext(ctx, "Hello World")
+
+	var got map[string]any
+	if err := json.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got[slogdriver.TraceKey] != "projects/test-project/traces/105445aa7843bc8bf206b12000100000" {
+		t.Errorf("unexpected trace: %v", got[slogdriver.TraceKey])
+	}
+	if _, ok := got[slogdriver.SpanIDKey]; ok {
+		t.Errorf("log should not have key=%s, got %v", slogdriver.SpanIDKey, got)
+	}
+}
+
+func TestRegisterTraceExtractor(t *testing.T) {
+	slogdriver.RestoreTraceExtractors(t)
+	slogdriver.RegisterTraceExtractor(slogdriver.TraceExtractorFunc(func(ctx context.Context) (slogdriver.TraceContext, bool) {
+		return slogdriver.TraceContext{}, false
+	}))
+
+	extractors := slogdriver.DefaultTraceExtractors()
+	if len(extractors) != 3 {
+		t.Fatalf("DefaultTraceExtractors should have 3 extractors, got %d", len(extractors))
+	}
+	if _, ok := extractors[0].(slogdriver.OpenTelemetryTraceExtractor); !ok {
+		t.Errorf("first extractor should be OpenTelemetryTraceExtractor, got %T", extractors[0])
+	}
+	if _, ok := extractors[2].(slogdriver.HeaderTraceExtractor); !ok {
+		t.Errorf("last extractor should be HeaderTraceExtractor, got %T", extractors[2])
+	}
+}
+
+func TestCloudLoggingHandler_HandleTraceShouldNotOverrideUserTrace(t *testing.T) {
+	ctx := slogdriver.ContextWithTrace(context.Background(), slogdriver.TraceContext{TraceID: "105445aa7843bc8bf206b12000100000", SpanID: "0000000000000001"})
+	tests := map[string]func(logger *slog.Logger){
+		"record": func(logger *slog.Logger) {
+			logger.InfoContext(ctx, "Hello World", slog.String(slogdriver.TraceKey, "projects/test-project/traces/user"))
+		},
+		"WithAttrs": func(logger *slog.Logger) {
+			logger.With(slog.String(slogdriver.TraceKey, "projects/test-project/traces/user")).InfoContext(ctx, "Hello World")
+		},
+	}
+
+	for n, log := range tests {
+		t.Run(n, func(t *testing.T) {
+			var buf bytes.Buffer
+			log(slogdriver.New(&buf, slogdriver.HandlerOptions{ProjectID: "test-project"}))
+
+			got := buf.String()
+			if n := strings.Count(got, slogdriver.TraceKey); n != 1 {
+				t.Errorf("trace should appear once, got %d: %s", n, got)
+			}
+			if strings.Contains(got, slogdriver.SpanIDKey) {
+				t.Errorf("span id of the context should not be added, got %s", got)
+			}
+		})
+	}
+}
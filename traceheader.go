@@ -0,0 +1,126 @@
+package slogdriver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	CloudTraceContextHeader = "X-Cloud-Trace-Context"
+	TraceparentHeader       = "traceparent"
+)
+
+// TraceContext is the trace information propagated by the request headers.
+type TraceContext struct {
+	// TraceID is 32 lowercase hex characters.
+	TraceID string
+	// SpanID is 16 lowercase hex characters. It is empty when the header doesn't have span id.
+	SpanID  string
+	Sampled bool
+}
+
+type traceContextKey struct{}
+
+// ContextWithTrace returns a copy of ctx which carries tc.
+// The handler uses it when ctx doesn't have the span of a tracer SDK.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceFromContext returns the TraceContext stored by ContextWithTrace.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// TraceFromHeader parses traceparent or X-Cloud-Trace-Context header.
+// traceparent is preferred when both are valid.
+func TraceFromHeader(h http.Header) (TraceContext, bool) {
+	if v := h.Get(TraceparentHeader); v != "" {
+		if tc, err := ParseTraceparent(v); err == nil {
+			return tc, true
+		}
+	}
+
+	if v := h.Get(CloudTraceContextHeader); v != "" {
+		if tc, err := ParseCloudTraceContext(v); err == nil {
+			return tc, true
+		}
+	}
+
+	return TraceContext{}, false
+}
+
+// ParseCloudTraceContext parses X-Cloud-Trace-Context header value formatted as "TRACE_ID/SPAN_ID;o=OPTIONS".
+// SPAN_ID is decimal, so it is converted to hex.
+// https://cloud.google.com/trace/docs/trace-context#legacy-http-header
+func ParseCloudTraceContext(s string) (TraceContext, error) {
+	var tc TraceContext
+
+	s, options, _ := strings.Cut(s, ";")
+	traceID, spanID, hasSpanID := strings.Cut(s, "/")
+	if !isValidHexID(traceID, 32) {
+		return TraceContext{}, fmt.Errorf("invalid trace id of %s: %q", CloudTraceContextHeader, traceID)
+	}
+	tc.TraceID = strings.ToLower(traceID)
+
+	if hasSpanID && spanID != "" {
+		id, err := strconv.ParseUint(spanID, 10, 64)
+		if err != nil {
+			return TraceContext{}, fmt.Errorf("invalid span id of %s: %q", CloudTraceContextHeader, spanID)
+		}
+		if id != 0 {
+			tc.SpanID = fmt.Sprintf("%016x", id)
+		}
+	}
+
+	tc.Sampled = options == "o=1"
+	return tc, nil
+}
+
+// ParseTraceparent parses W3C traceparent header value formatted as "VERSION-TRACE_ID-PARENT_ID-FLAGS".
+// https://www.w3.org/TR/trace-context/#traceparent-header
+func ParseTraceparent(s string) (TraceContext, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 {
+		return TraceContext{}, fmt.Errorf("invalid %s: %q", TraceparentHeader, s)
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || !isHex(version) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return TraceContext{}, fmt.Errorf("invalid version of %s: %q", TraceparentHeader, s)
+	}
+	if !isValidHexID(traceID, 32) || traceID != strings.ToLower(traceID) {
+		return TraceContext{}, fmt.Errorf("invalid trace id of %s: %q", TraceparentHeader, traceID)
+	}
+	if !isValidHexID(spanID, 16) || spanID != strings.ToLower(spanID) {
+		return TraceContext{}, fmt.Errorf("invalid parent id of %s: %q", TraceparentHeader, spanID)
+	}
+	if len(flags) != 2 || !isHex(flags) {
+		return TraceContext{}, fmt.Errorf("invalid flags of %s: %q", TraceparentHeader, flags)
+	}
+	f, _ := strconv.ParseUint(flags, 16, 8)
+
+	return TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: f&1 == 1,
+	}, nil
+}
+
+// isValidHexID reports whether s is n hex characters and not all zeros.
+func isValidHexID(s string, n int) bool {
+	return len(s) == n && isHex(s) && strings.Trim(s, "0") != ""
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,209 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+)
+
+func TestParseCloudTraceContext(t *testing.T) {
+	tests := map[string]struct {
+		header    string
+		expect    slogdriver.TraceContext
+		expectErr bool
+	}{
+		"sampled": {
+			header: "105445aa7843bc8bf206b12000100000/1;o=1",
+			expect: slogdriver.TraceContext{
+				TraceID: "105445aa7843bc8bf206b12000100000",
+				SpanID:  "0000000000000001",
+				Sampled: true,
+			},
+		},
+		"decimal span id": {
+			header: "105445AA7843BC8BF206B12000100000/18446744073709551615;o=0",
+			expect: slogdriver.TraceContext{
+				TraceID: "105445aa7843bc8bf206b12000100000",
+				SpanID:  "ffffffffffffffff",
+			},
+		},
+		"without options": {
+			header: "105445aa7843bc8bf206b12000100000/1234",
+			expect: slogdriver.TraceContext{
+				TraceID: "105445aa7843bc8bf206b12000100000",
+				SpanID:  "00000000000004d2",
+			},
+		},
+		"without span id": {
+			header: "105445aa7843bc8bf206b12000100000",
+			expect: slogdriver.TraceContext{
+				TraceID: "105445aa7843bc8bf206b12000100000",
+			},
+		},
+		"invalid trace id": {
+			header:    "invalid/1;o=1",
+			expectErr: true,
+		},
+		"zero trace id": {
+			header:    "00000000000000000000000000000000/1;o=1",
+			expectErr: true,
+		},
+		"invalid span id": {
+			header:    "105445aa7843bc8bf206b12000100000/abc;o=1",
+			expectErr: true,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			got, err := slogdriver.ParseCloudTraceContext(tt.header)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if got != tt.expect {
+				t.Errorf("expected %+v, got %+v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := map[string]struct {
+		header    string
+		expect    slogdriver.TraceContext
+		expectErr bool
+	}{
+		"sampled": {
+			header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expect: slogdriver.TraceContext{
+				TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanID:  "00f067aa0ba902b7",
+				Sampled: true,
+			},
+		},
+		"not sampled": {
+			header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expect: slogdriver.TraceContext{
+				TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanID:  "00f067aa0ba902b7",
+			},
+		},
+		"future version": {
+			header: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			expect: slogdriver.TraceContext{
+				TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanID:  "00f067aa0ba902b7",
+				Sampled: true,
+			},
+		},
+		"invalid version": {
+			header:    "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectErr: true,
+		},
+		"zero trace id": {
+			header:    "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			expectErr: true,
+		},
+		"zero parent id": {
+			header:    "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			expectErr: true,
+		},
+		"uppercase": {
+			header:    "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01",
+			expectErr: true,
+		},
+		"too few fields": {
+			header:    "00-4bf92f3577b34da6a3ce929d0e0e4736",
+			expectErr: true,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			got, err := slogdriver.ParseTraceparent(tt.header)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if got != tt.expect {
+				t.Errorf("expected %+v, got %+v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestTraceFromHeader_PreferTraceparent(t *testing.T) {
+	h := http.Header{}
+	h.Set(slogdriver.CloudTraceContextHeader, "105445aa7843bc8bf206b12000100000/1;o=1")
+	h.Set(slogdriver.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	got, ok := slogdriver.TraceFromHeader(h)
+	if !ok {
+		t.Fatal("trace should be found")
+	}
+	if got.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("unexpected trace id: %s", got.TraceID)
+	}
+}
+
+func TestCloudLoggingHandler_HandleTraceShouldHaveContextTraceKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{ProjectID: "test-project"})
+
+	ctx := slogdriver.ContextWithTrace(context.Background(), slogdriver.TraceContext{
+		TraceID: "105445aa7843bc8bf206b12000100000",
+		SpanID:  "0000000000000001",
+		Sampled: true,
+	})
+	logger.InfoContext(ctx, "Hello World")
+
+	var got map[string]any
+	if err := json.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got[slogdriver.TraceKey] != "projects/test-project/traces/105445aa7843bc8bf206b12000100000" {
+		t.Errorf("unexpected trace: %v", got[slogdriver.TraceKey])
+	}
+	if got[slogdriver.SpanIDKey] != "0000000000000001" {
+		t.Errorf("unexpected span id: %v", got[slogdriver.SpanIDKey])
+	}
+	if got[slogdriver.TraceSampledKey] != true {
+		t.Errorf("unexpected trace sampled: %v", got[slogdriver.TraceSampledKey])
+	}
+}
+
+func TestMiddleware_TraceHeader(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{ProjectID: "test-project"})
+
+	handler := slogdriver.Middleware(logger, slogdriver.MiddlewareOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(slogdriver.CloudTraceContextHeader, "105445aa7843bc8bf206b12000100000/1;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var got map[string]any
+	if err := json.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got[slogdriver.TraceKey] != "projects/test-project/traces/105445aa7843bc8bf206b12000100000" {
+		t.Errorf("unexpected trace: %v", got[slogdriver.TraceKey])
+	}
+}
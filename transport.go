@@ -0,0 +1,92 @@
+package slogdriver
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// Transport is an http.RoundTripper which logs one httpRequest entry per outgoing request.
+// The entry is logged with the request context, so the active trace is attached to it.
+type Transport struct {
+	// Base is the underlying RoundTripper.
+	// If Base is nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Logger is the logger to write the entries.
+	// If Logger is nil, slog.Default() is used.
+	Logger *slog.Logger
+
+	// Message is the message of the entry.
+	// If Message is empty, "http client request" is used.
+	Message string
+
+	// StatusLevel decides the level of the entry from the response status code.
+	// If StatusLevel is nil, DefaultStatusLevel is used.
+	// Requests which failed without response are always logged with LevelError.
+	StatusLevel StatusLevelFunc
+
+	// IsGKE formats the latency for GKE instead of Cloud Run and GAE.
+	// See MakeLatency.
+	IsGKE bool
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var serverAddr string
+	ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			serverAddr = info.Conn.RemoteAddr().String()
+		},
+	})
+
+	start := time.Now()
+	res, err := t.base().RoundTrip(req.WithContext(ctx))
+	latency := time.Since(start)
+
+	p := MakeHTTPPayload(req, res)
+	p.Latency = MakeLatency(latency, t.IsGKE)
+	p.ServerIP = serverAddr
+	if ip, _, splitErr := net.SplitHostPort(serverAddr); splitErr == nil {
+		p.ServerIP = ip
+	}
+	if res != nil && res.Proto != "" {
+		p.Protocol = res.Proto
+	}
+
+	if err != nil {
+		t.logger().LogAttrs(req.Context(), LevelError, t.message(), MakeHTTPAttrFromHTTPPayload(p), slog.Any("error", err))
+		return res, err
+	}
+
+	statusLevel := t.StatusLevel
+	if statusLevel == nil {
+		statusLevel = DefaultStatusLevel
+	}
+	t.logger().LogAttrs(req.Context(), statusLevel(res.StatusCode), t.message(), MakeHTTPAttrFromHTTPPayload(p))
+	return res, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *Transport) logger() *slog.Logger {
+	if t.Logger == nil {
+		return slog.Default()
+	}
+	return t.Logger
+}
+
+func (t *Transport) message() string {
+	if t.Message == "" {
+		return "http client request"
+	}
+	return t.Message
+}
@@ -0,0 +1,110 @@
+package slogdriver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kitagry/slogdriver"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Hello World"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{ProjectID: "test-project"})
+	client := &http.Client{Transport: &slogdriver.Transport{Logger: logger}}
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+	otel.SetTracerProvider(tp)
+
+	ctx, span := otel.Tracer("test-tracer").Start(context.Background(), "test-span")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/path", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	var got struct {
+		Severity    string                 `json:"severity"`
+		Message     string                 `json:"message"`
+		Trace       string                 `json:"logging.googleapis.com/trace"`
+		HTTPRequest slogdriver.HTTPPayload `json:"httpRequest"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got.Severity != "ERROR" {
+		t.Errorf("unexpected severity: %s", got.Severity)
+	}
+	if got.Message != "http client request" {
+		t.Errorf("unexpected message: %s", got.Message)
+	}
+	expectedTrace := "projects/test-project/traces/" + span.SpanContext().TraceID().String()
+	if got.Trace != expectedTrace {
+		t.Errorf("trace expected %s, got %s", expectedTrace, got.Trace)
+	}
+
+	p := got.HTTPRequest
+	if p.RequestURL != server.URL+"/path" {
+		t.Errorf("unexpected requestUrl: %s", p.RequestURL)
+	}
+	if p.Status != http.StatusInternalServerError {
+		t.Errorf("unexpected status: %d", p.Status)
+	}
+	if p.ResponseSize != "11" {
+		t.Errorf("unexpected responseSize: %s", p.ResponseSize)
+	}
+	if p.ServerIP != "127.0.0.1" {
+		t.Errorf("unexpected serverIp: %s", p.ServerIP)
+	}
+	if p.Latency == nil {
+		t.Error("latency should be set")
+	}
+}
+
+func TestTransport_Error(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogdriver.New(&buf, slogdriver.HandlerOptions{})
+	client := &http.Client{Transport: &slogdriver.Transport{Logger: logger}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("request should fail")
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode json: %+v", err)
+	}
+
+	if got[slogdriver.SeverityKey] != "ERROR" {
+		t.Errorf("unexpected severity: %s", got[slogdriver.SeverityKey])
+	}
+	if got["error"] != context.Canceled.Error() {
+		t.Errorf("unexpected error: %v", got["error"])
+	}
+}